@@ -0,0 +1,219 @@
+// Package audit writes a tamper-evident log of API requests. Every entry is one
+// JSON line carrying a sequence number and the SHA-256 of the previous line, so
+// removing or editing a line breaks the chain. Sensitive values are recorded as
+// HMAC-SHA256 digests instead of in clear.
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/dezween/ElevexaCodingChallenge2/internal/storage"
+)
+
+// hmacPrefix marks values replaced by their HMAC.
+const hmacPrefix = "hmac-sha256:"
+
+// hmacKeyPath is the storage path of the audit HMAC key.
+const hmacKeyPath = "core/audit-hmac-key"
+
+// hmacKeySize is the size of the audit HMAC key in bytes.
+const hmacKeySize = 32
+
+var (
+	// ErrChainBroken is returned by Verify when the hash chain does not hold.
+	ErrChainBroken = errors.New("audit: hash chain broken")
+	// ErrMaybeWritten is wrapped by sinks whose failed Write may still have stored
+	// the line, e.g. when the file was written but fsync failed.
+	ErrMaybeWritten = errors.New("audit: entry may have been written")
+)
+
+// Request describes the request side of an audited exchange.
+type Request struct {
+	ID         string `json:"id"`                    // Random request ID
+	Route      string `json:"route"`                 // Route name, e.g. "encrypt"
+	Method     string `json:"method"`                // HTTP method
+	Path       string `json:"path"`                  // Request path
+	KeyName    string `json:"key_name,omitempty"`    // Transit key name, if any
+	KeyVersion int    `json:"key_version,omitempty"` // Key version used, if known
+	Accessor   string `json:"accessor,omitempty"`    // HMAC of the token accessor
+	ClientIP   string `json:"client_ip"`             // Remote address of the client
+}
+
+// Response describes the outcome of an audited exchange.
+type Response struct {
+	Status int    `json:"status"`          // HTTP status code
+	Result string `json:"result"`          // "success", "denied" or "error"
+	Error  string `json:"error,omitempty"` // Error message returned to the client
+}
+
+// Entry is one line of the audit log.
+type Entry struct {
+	Seq      uint64    `json:"seq"`       // 1 for the first entry, then consecutive
+	Time     time.Time `json:"time"`      // When the exchange completed
+	Request  Request   `json:"request"`   // Request details
+	Response Response  `json:"response"`  // Response details
+	PrevHash string    `json:"prev_hash"` // Hex SHA-256 of the previous line; empty for seq 1
+}
+
+// Sink receives encoded audit lines (without trailing newline).
+type Sink interface {
+	Write(line []byte) error
+}
+
+// chainTail is implemented by sinks that already hold entries, so a restarted
+// logger continues their chain instead of starting a new one.
+type chainTail interface {
+	Tail() (seq uint64, hash string)
+}
+
+// Logger appends entries to its sinks, linking each to the previous one.
+// It is safe for concurrent use.
+type Logger struct {
+	mu      sync.Mutex
+	sinks   []Sink
+	hmacKey func() ([]byte, error)
+	seq     uint64
+	prev    string
+}
+
+// NewLogger returns a Logger writing to sinks. hmacKey supplies the key used to
+// HMAC sensitive fields. The chain continues from the first sink that already
+// holds entries.
+func NewLogger(hmacKey func() ([]byte, error), sinks ...Sink) *Logger {
+	l := &Logger{sinks: sinks, hmacKey: hmacKey}
+	for _, s := range sinks {
+		if t, ok := s.(chainTail); ok {
+			if seq, hash := t.Tail(); seq > 0 {
+				l.seq, l.prev = seq, hash
+				break
+			}
+		}
+	}
+	return l
+}
+
+// HMAC returns the keyed digest under which value appears in the log,
+// e.g. to search the log for a known token accessor.
+func (l *Logger) HMAC(value string) (string, error) {
+	key, err := l.hmacKey()
+	if err != nil {
+		return "", fmt.Errorf("audit: failed to load hmac key: %w", err)
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(value))
+	return hmacPrefix + hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// Log HMACs the sensitive fields of e, assigns its sequence number and previous
+// hash, and writes it to every sink. The chain advances as soon as any sink may
+// have stored the entry, so a failing sink cannot make the next entry reuse its
+// sequence number in the others; the write errors are still returned.
+func (l *Logger) Log(e Entry) error {
+	if e.Request.Accessor != "" {
+		digest, err := l.HMAC(e.Request.Accessor)
+		if err != nil {
+			return err
+		}
+		e.Request.Accessor = digest
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	e.Seq = l.seq + 1
+	e.PrevHash = l.prev
+	line, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("audit: failed to encode entry: %w", err)
+	}
+	var errs []error
+	stored := false
+	for _, s := range l.sinks {
+		err := s.Write(line)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("audit: failed to write entry: %w", err))
+		}
+		stored = stored || err == nil || errors.Is(err, ErrMaybeWritten)
+	}
+	if stored {
+		l.seq = e.Seq
+		l.prev = hashLine(line)
+	}
+	return errors.Join(errs...)
+}
+
+// hashLine returns the hex SHA-256 of an encoded entry.
+func hashLine(line []byte) string {
+	sum := sha256.Sum256(line)
+	return hex.EncodeToString(sum[:])
+}
+
+// Verify walks the chain in r and returns the number of valid entries.
+// It fails if an entry is malformed, out of sequence, or does not reference the
+// hash of the line before it. Truncation after the last entry is not detectable
+// from the log alone.
+func Verify(r io.Reader) (int, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	var (
+		count int
+		prev  string
+	)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(line, &e); err != nil {
+			return count, fmt.Errorf("%w: line %d is not a valid entry: %v", ErrChainBroken, count+1, err)
+		}
+		if e.Seq != uint64(count+1) {
+			return count, fmt.Errorf("%w: expected seq %d, got %d", ErrChainBroken, count+1, e.Seq)
+		}
+		if e.PrevHash != prev {
+			return count, fmt.Errorf("%w: entry %d does not match the hash of entry %d", ErrChainBroken, e.Seq, count)
+		}
+		prev = hashLine(line)
+		count++
+	}
+	if err := scanner.Err(); err != nil {
+		return count, fmt.Errorf("audit: failed to read log: %w", err)
+	}
+	return count, nil
+}
+
+// hmacKeyMu serializes creation of the HMAC key.
+var hmacKeyMu sync.Mutex
+
+// LoadOrCreateHMACKey returns the audit HMAC key kept in s, generating and
+// storing a new one on first use. s is expected to be the barrier so the key is
+// encrypted at rest.
+func LoadOrCreateHMACKey(s storage.Storage) ([]byte, error) {
+	hmacKeyMu.Lock()
+	defer hmacKeyMu.Unlock()
+	key, err := s.Get(hmacKeyPath)
+	if err == nil {
+		return key, nil
+	}
+	if !errors.Is(err, storage.ErrNotFound) {
+		return nil, err
+	}
+	key = make([]byte, hmacKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("audit: failed to generate hmac key: %w", err)
+	}
+	if err := s.Put(hmacKeyPath, key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
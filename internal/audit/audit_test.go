@@ -0,0 +1,143 @@
+package audit
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dezween/ElevexaCodingChallenge2/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testKey() ([]byte, error) { return []byte("0123456789abcdef0123456789abcdef"), nil }
+
+// writeEntries logs n entries for the given accessor and returns the log lines.
+func writeEntries(t *testing.T, n int) []string {
+	t.Helper()
+	var buf bytes.Buffer
+	l := NewLogger(testKey, NewWriter(&buf))
+	for i := 0; i < n; i++ {
+		require.NoError(t, l.Log(Entry{
+			Request:  Request{Route: "encrypt", KeyName: "k", Accessor: "acc"},
+			Response: Response{Status: 200, Result: "success"},
+		}))
+	}
+	return strings.Split(strings.TrimSpace(buf.String()), "\n")
+}
+
+func TestLogger_HMACsAccessor(t *testing.T) {
+	lines := writeEntries(t, 1)
+	require.Len(t, lines, 1)
+	assert.NotContains(t, lines[0], `"accessor":"acc"`)
+
+	l := NewLogger(testKey)
+	digest, err := l.HMAC("acc")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(digest, hmacPrefix))
+	assert.Contains(t, lines[0], digest)
+}
+
+func TestVerify_TableDriven(t *testing.T) {
+	lines := writeEntries(t, 4)
+	join := func(ls ...string) string { return strings.Join(ls, "\n") + "\n" }
+
+	tests := []struct {
+		name      string
+		log       string
+		wantCount int
+		wantError string
+	}{
+		{"intact", join(lines...), 4, ""},
+		{"empty", "", 0, ""},
+		{"deleted middle entry", join(lines[0], lines[2], lines[3]), 1, "expected seq 2"},
+		{"deleted first entry", join(lines[1:]...), 0, "expected seq 1"},
+		{"edited entry", join(lines[0], strings.Replace(lines[1], `"status":200`, `"status":403`, 1), lines[2], lines[3]), 2, "does not match"},
+		{"reordered", join(lines[0], lines[2], lines[1], lines[3]), 1, "expected seq 2"},
+		{"garbage", join(lines[0], "not json"), 1, "not a valid entry"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			count, err := Verify(strings.NewReader(tt.log))
+			assert.Equal(t, tt.wantCount, count)
+			if tt.wantError != "" {
+				assert.ErrorIs(t, err, ErrChainBroken)
+				assert.ErrorContains(t, err, tt.wantError)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestFileSink_ContinuesChainAfterRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	for i := 0; i < 2; i++ {
+		sink, err := NewFile(path)
+		require.NoError(t, err)
+		l := NewLogger(testKey, sink)
+		require.NoError(t, l.Log(Entry{Request: Request{Route: "decrypt"}}))
+		require.NoError(t, l.Log(Entry{Request: Request{Route: "decrypt"}}))
+		require.NoError(t, sink.Close())
+	}
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	count, err := Verify(f)
+	require.NoError(t, err)
+	assert.Equal(t, 4, count)
+
+	// A tampered log is not extended.
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, bytes.Replace(data, []byte(`"seq":2`), []byte(`"seq":9`), 1), 0o600))
+	_, err = NewFile(path)
+	assert.ErrorIs(t, err, ErrChainBroken)
+}
+
+// failingSink rejects every line without storing it.
+type failingSink struct{}
+
+func (failingSink) Write([]byte) error { return errors.New("broken pipe") }
+
+func TestLogger_FailingSinkKeepsChain(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	sink, err := NewFile(path)
+	require.NoError(t, err)
+	l := NewLogger(testKey, sink, failingSink{})
+	for i := 0; i < 3; i++ {
+		assert.ErrorContains(t, l.Log(Entry{Request: Request{Route: "encrypt"}}), "broken pipe")
+	}
+	require.NoError(t, sink.Close())
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	count, err := Verify(f)
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+	_, err = NewFile(path)
+	assert.NoError(t, err, "the log must still be accepted at startup")
+
+	// When no sink stored the entry, its sequence number is reused.
+	l = NewLogger(testKey, failingSink{})
+	assert.Error(t, l.Log(Entry{}))
+	var buf bytes.Buffer
+	l.sinks = []Sink{NewWriter(&buf)}
+	require.NoError(t, l.Log(Entry{}))
+	assert.Contains(t, buf.String(), `"seq":1,`)
+}
+
+func TestLoadOrCreateHMACKey(t *testing.T) {
+	s := storage.NewMemory()
+	key, err := LoadOrCreateHMACKey(s)
+	require.NoError(t, err)
+	assert.Len(t, key, hmacKeySize)
+	again, err := LoadOrCreateHMACKey(s)
+	require.NoError(t, err)
+	assert.Equal(t, key, again)
+}
@@ -0,0 +1,124 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"sync"
+)
+
+// Writer is a Sink that writes one line per entry to an io.Writer, e.g. os.Stdout.
+type Writer struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewWriter returns a Sink writing to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// Write writes line followed by a newline. A write that fails after writing some
+// bytes is reported as ErrMaybeWritten.
+func (s *Writer) Write(line []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n, err := s.w.Write(append(line, '\n'))
+	if err != nil && n > 0 {
+		return fmt.Errorf("%w: %w", ErrMaybeWritten, err)
+	}
+	return err
+}
+
+// File is a Sink that appends entries to a file and fsyncs after every entry.
+type File struct {
+	mu   sync.Mutex
+	f    *os.File
+	seq  uint64 // Sequence number of the last entry when the file was opened
+	hash string // Hash of the last entry when the file was opened
+}
+
+// NewFile opens (creating if needed) the audit log at path for appending.
+// The chain of an existing log is verified, so a tampered log is not extended.
+func NewFile(path string) (*File, error) {
+	seq, hash, err := readTail(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("audit: failed to open log: %w", err)
+	}
+	return &File{f: f, seq: seq, hash: hash}, nil
+}
+
+// readTail verifies the log at path and returns the sequence number and hash of
+// its last entry. A missing log yields zero values.
+func readTail(path string) (uint64, string, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, "", nil
+	}
+	if err != nil {
+		return 0, "", fmt.Errorf("audit: failed to open log: %w", err)
+	}
+	defer f.Close()
+	if _, err := Verify(f); err != nil {
+		return 0, "", err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return 0, "", fmt.Errorf("audit: failed to read log: %w", err)
+	}
+	var last []byte
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) > 0 {
+			last = append(last[:0], scanner.Bytes()...)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, "", fmt.Errorf("audit: failed to read log: %w", err)
+	}
+	if last == nil {
+		return 0, "", nil
+	}
+	var e Entry
+	if err := json.Unmarshal(last, &e); err != nil {
+		return 0, "", fmt.Errorf("audit: failed to decode last entry: %w", err)
+	}
+	return e.Seq, hashLine(last), nil
+}
+
+// Tail returns the sequence number and hash of the last entry found when the file was opened.
+func (s *File) Tail() (uint64, string) {
+	return s.seq, s.hash
+}
+
+// Write appends line and a newline, then fsyncs the file. A partial write or a
+// failed fsync is reported as ErrMaybeWritten.
+func (s *File) Write(line []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if n, err := s.f.Write(append(line, '\n')); err != nil {
+		if n > 0 {
+			return fmt.Errorf("%w: %w", ErrMaybeWritten, err)
+		}
+		return err
+	}
+	if err := s.f.Sync(); err != nil {
+		return fmt.Errorf("%w: fsync failed: %w", ErrMaybeWritten, err)
+	}
+	return nil
+}
+
+// Close closes the underlying file.
+func (s *File) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.f.Close()
+}
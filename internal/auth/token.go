@@ -0,0 +1,191 @@
+// Package auth implements bearer tokens for the transit API. Tokens are stored
+// hashed, so the storage backend never holds a usable token.
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dezween/ElevexaCodingChallenge2/internal/policy"
+	"github.com/dezween/ElevexaCodingChallenge2/internal/storage"
+)
+
+const (
+	// tokenPrefix marks token IDs issued by this service.
+	tokenPrefix = "kt."
+	// tokenIDPath maps the SHA-256 of a token ID to its entry.
+	tokenIDPath = "auth/token/id/"
+	// tokenAccessorPath maps an accessor to the SHA-256 of its token ID.
+	tokenAccessorPath = "auth/token/accessor/"
+)
+
+var (
+	// ErrTokenNotFound is returned for unknown, revoked or expired tokens.
+	ErrTokenNotFound = errors.New("auth: token not found")
+	// ErrInvalidTTL is returned when a non-positive TTL is requested.
+	ErrInvalidTTL = errors.New("auth: ttl must be positive")
+)
+
+// Token is the stored metadata of a token. The token ID itself is never stored.
+type Token struct {
+	Accessor  string    `json:"accessor"`             // Non-secret handle for lookup and revocation
+	Policies  []string  `json:"policies"`             // Names of ACL policies attached to the token
+	Root      bool      `json:"root"`                 // Root tokens never expire
+	CreatedAt time.Time `json:"created_at"`           // Creation time
+	ExpiresAt time.Time `json:"expires_at,omitempty"` // Zero for root tokens
+}
+
+// Expired reports whether the token has expired at the given time.
+func (t Token) Expired(now time.Time) bool {
+	return !t.ExpiresAt.IsZero() && !now.Before(t.ExpiresAt)
+}
+
+// TTL returns the remaining lifetime of the token at the given time, or 0 if it does not expire.
+func (t Token) TTL(now time.Time) time.Duration {
+	if t.ExpiresAt.IsZero() {
+		return 0
+	}
+	return max(t.ExpiresAt.Sub(now), 0)
+}
+
+// TokenStore issues, looks up and revokes tokens kept in a Storage backend.
+type TokenStore struct {
+	storage storage.Storage
+	now     func() time.Time
+}
+
+// NewTokenStore returns a token store on top of the given storage.
+func NewTokenStore(s storage.Storage) *TokenStore {
+	return &TokenStore{storage: s, now: time.Now}
+}
+
+// CreateRoot issues a root token that never expires and carries the root policy.
+func (ts *TokenStore) CreateRoot() (string, Token, error) {
+	return ts.create(Token{Root: true, Policies: []string{policy.RootName}})
+}
+
+// Create issues a token with the given policies that expires after ttl.
+func (ts *TokenStore) Create(ttl time.Duration, policies []string) (string, Token, error) {
+	if ttl <= 0 {
+		return "", Token{}, ErrInvalidTTL
+	}
+	return ts.create(Token{Policies: policies, ExpiresAt: ts.now().Add(ttl).UTC()})
+}
+
+// Lookup returns the token for the given ID. Expired tokens are revoked and reported as not found.
+func (ts *TokenStore) Lookup(id string) (Token, error) {
+	if !strings.HasPrefix(id, tokenPrefix) {
+		return Token{}, ErrTokenNotFound
+	}
+	hash := hashID(id)
+	token, err := ts.load(hash)
+	if err != nil {
+		return Token{}, err
+	}
+	if token.Expired(ts.now()) {
+		if err := ts.delete(hash, token.Accessor); err != nil {
+			return Token{}, err
+		}
+		return Token{}, ErrTokenNotFound
+	}
+	return token, nil
+}
+
+// Revoke deletes the token with the given ID.
+func (ts *TokenStore) Revoke(id string) error {
+	hash := hashID(id)
+	token, err := ts.load(hash)
+	if err != nil {
+		return err
+	}
+	return ts.delete(hash, token.Accessor)
+}
+
+// RevokeAccessor deletes the token with the given accessor.
+func (ts *TokenStore) RevokeAccessor(accessor string) error {
+	data, err := ts.storage.Get(tokenAccessorPath + accessor)
+	if errors.Is(err, storage.ErrNotFound) {
+		return ErrTokenNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("auth: failed to read accessor: %w", err)
+	}
+	return ts.delete(string(data), accessor)
+}
+
+// create generates a token ID and accessor for the given metadata and stores it.
+func (ts *TokenStore) create(token Token) (string, Token, error) {
+	secret, err := randomString()
+	if err != nil {
+		return "", Token{}, err
+	}
+	accessor, err := randomString()
+	if err != nil {
+		return "", Token{}, err
+	}
+	id := tokenPrefix + secret
+	token.Accessor = accessor
+	token.CreatedAt = ts.now().UTC()
+
+	hash := hashID(id)
+	data, err := json.Marshal(token)
+	if err != nil {
+		return "", Token{}, fmt.Errorf("auth: failed to encode token: %w", err)
+	}
+	if err := ts.storage.Put(tokenIDPath+hash, data); err != nil {
+		return "", Token{}, fmt.Errorf("auth: failed to store token: %w", err)
+	}
+	if err := ts.storage.Put(tokenAccessorPath+accessor, []byte(hash)); err != nil {
+		return "", Token{}, fmt.Errorf("auth: failed to store accessor: %w", err)
+	}
+	return id, token, nil
+}
+
+// load reads the token entry for the given ID hash.
+func (ts *TokenStore) load(hash string) (Token, error) {
+	data, err := ts.storage.Get(tokenIDPath + hash)
+	if errors.Is(err, storage.ErrNotFound) {
+		return Token{}, ErrTokenNotFound
+	}
+	if err != nil {
+		return Token{}, fmt.Errorf("auth: failed to read token: %w", err)
+	}
+	var token Token
+	if err := json.Unmarshal(data, &token); err != nil {
+		return Token{}, fmt.Errorf("auth: failed to decode token: %w", err)
+	}
+	return token, nil
+}
+
+// delete removes a token entry and its accessor mapping.
+func (ts *TokenStore) delete(hash, accessor string) error {
+	if err := ts.storage.Delete(tokenIDPath + hash); err != nil {
+		return fmt.Errorf("auth: failed to delete token: %w", err)
+	}
+	if err := ts.storage.Delete(tokenAccessorPath + accessor); err != nil {
+		return fmt.Errorf("auth: failed to delete accessor: %w", err)
+	}
+	return nil
+}
+
+// hashID returns the hex SHA-256 of a token ID, used as its storage key.
+func hashID(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return hex.EncodeToString(sum[:])
+}
+
+// randomString returns 32 random bytes encoded as unpadded base64url.
+func randomString() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("auth: failed to generate random value: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
@@ -0,0 +1,96 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dezween/ElevexaCodingChallenge2/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenStore_Lifecycle(t *testing.T) {
+	backend := storage.NewMemory()
+	ts := NewTokenStore(backend)
+
+	rootID, root, err := ts.CreateRoot()
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(rootID, "kt."))
+	assert.True(t, root.Root)
+	assert.True(t, root.ExpiresAt.IsZero())
+	assert.Equal(t, []string{"root"}, root.Policies)
+
+	got, err := ts.Lookup(rootID)
+	require.NoError(t, err)
+	assert.Equal(t, root.Accessor, got.Accessor)
+
+	// The token ID must not appear anywhere in storage.
+	paths, err := backend.List("")
+	require.NoError(t, err)
+	for _, p := range paths {
+		value, err := backend.Get(p)
+		require.NoError(t, err)
+		assert.NotContains(t, p, strings.TrimPrefix(rootID, "kt."))
+		assert.NotContains(t, string(value), strings.TrimPrefix(rootID, "kt."))
+	}
+
+	id, token, err := ts.Create(time.Hour, []string{"payments"})
+	require.NoError(t, err)
+	assert.False(t, token.Root)
+	assert.Equal(t, []string{"payments"}, token.Policies)
+	assert.InDelta(t, time.Hour.Seconds(), token.TTL(time.Now()).Seconds(), 5)
+
+	require.NoError(t, ts.Revoke(id))
+	_, err = ts.Lookup(id)
+	assert.ErrorIs(t, err, ErrTokenNotFound)
+	assert.ErrorIs(t, ts.Revoke(id), ErrTokenNotFound)
+
+	require.NoError(t, ts.RevokeAccessor(root.Accessor))
+	_, err = ts.Lookup(rootID)
+	assert.ErrorIs(t, err, ErrTokenNotFound)
+	assert.ErrorIs(t, ts.RevokeAccessor(root.Accessor), ErrTokenNotFound)
+}
+
+func TestTokenStore_Expiry(t *testing.T) {
+	backend := storage.NewMemory()
+	ts := NewTokenStore(backend)
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	ts.now = func() time.Time { return now }
+
+	id, _, err := ts.Create(time.Minute, nil)
+	require.NoError(t, err)
+	_, err = ts.Lookup(id)
+	require.NoError(t, err)
+
+	now = now.Add(time.Minute)
+	_, err = ts.Lookup(id)
+	assert.ErrorIs(t, err, ErrTokenNotFound)
+
+	paths, err := backend.List("auth/")
+	require.NoError(t, err)
+	assert.Empty(t, paths, "expired tokens are removed on lookup")
+}
+
+func TestTokenStore_Errors_TableDriven(t *testing.T) {
+	ts := NewTokenStore(storage.NewMemory())
+
+	tests := []struct {
+		name string
+		id   string
+	}{
+		{"empty", ""},
+		{"foreign prefix", "hvs.abc"},
+		{"unknown", "kt.unknown"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ts.Lookup(tt.id)
+			assert.ErrorIs(t, err, ErrTokenNotFound)
+		})
+	}
+
+	_, _, err := ts.Create(0, nil)
+	assert.ErrorIs(t, err, ErrInvalidTTL)
+}
@@ -0,0 +1,231 @@
+// Package barrier provides an encrypting Storage wrapper that protects every entry
+// with a master key and refuses all access while sealed.
+package barrier
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/dezween/ElevexaCodingChallenge2/internal/storage"
+)
+
+// KeySize is the required size of the master key in bytes (AES-256).
+const KeySize = 32
+
+// checkPath is the storage path of the entry used to verify the master key on unseal.
+const checkPath = "core/barrier-check"
+
+// checkValue is the known plaintext stored (encrypted) at checkPath.
+const checkValue = "kyber-transit-barrier-v1"
+
+// entryVersion prefixes every encrypted entry and identifies the entry layout.
+const entryVersion = 1
+
+var (
+	// ErrSealed is returned for any storage access while the barrier is sealed.
+	ErrSealed = errors.New("barrier: sealed")
+	// ErrNotInitialized is returned by Unseal before a master key has been set.
+	ErrNotInitialized = errors.New("barrier: not initialized")
+	// ErrAlreadyInitialized is returned by Initialize when a master key already exists.
+	ErrAlreadyInitialized = errors.New("barrier: already initialized")
+	// ErrInvalidKey is returned when the supplied master key is malformed or wrong.
+	ErrInvalidKey = errors.New("barrier: invalid master key")
+)
+
+// Barrier is a storage.Storage that encrypts every value with AES-256-GCM under
+// a master key before handing it to the underlying backend. Entries are bound to
+// their path through the AEAD additional data, so they cannot be swapped.
+//
+// A new Barrier starts sealed: the master key is only held in memory after Unseal,
+// or after enough shares have been passed to SubmitUnsealKey.
+type Barrier struct {
+	backend       storage.Storage
+	mu            sync.RWMutex
+	aead          cipher.AEAD // nil while sealed
+	pendingShares [][]byte    // unseal key shares submitted so far
+}
+
+// New returns a sealed barrier on top of the given backend.
+func New(backend storage.Storage) *Barrier {
+	return &Barrier{backend: backend}
+}
+
+// Sealed reports whether the barrier is sealed.
+func (b *Barrier) Sealed() bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.aead == nil
+}
+
+// Initialized reports whether a master key has been set up in the backend.
+func (b *Barrier) Initialized() (bool, error) {
+	_, err := b.backend.Get(checkPath)
+	if errors.Is(err, storage.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("barrier: failed to read check entry: %w", err)
+	}
+	return true, nil
+}
+
+// Initialize sets up the barrier with the given master key. The barrier stays sealed.
+func (b *Barrier) Initialize(masterKey []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.initializeLocked(masterKey)
+}
+
+// initializeLocked writes the check entry for masterKey. Callers must hold b.mu.
+func (b *Barrier) initializeLocked(masterKey []byte) error {
+	initialized, err := b.Initialized()
+	if err != nil {
+		return err
+	}
+	if initialized {
+		return ErrAlreadyInitialized
+	}
+	aead, err := newAEAD(masterKey)
+	if err != nil {
+		return err
+	}
+	sealed, err := encryptEntry(aead, checkPath, []byte(checkValue))
+	if err != nil {
+		return err
+	}
+	if err := b.backend.Put(checkPath, sealed); err != nil {
+		return fmt.Errorf("barrier: failed to write check entry: %w", err)
+	}
+	return nil
+}
+
+// Unseal verifies the master key against the check entry and, if it matches,
+// keeps it in memory so storage operations are permitted.
+func (b *Barrier) Unseal(masterKey []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.aead != nil {
+		return nil
+	}
+	data, err := b.backend.Get(checkPath)
+	if errors.Is(err, storage.ErrNotFound) {
+		return ErrNotInitialized
+	}
+	if err != nil {
+		return fmt.Errorf("barrier: failed to read check entry: %w", err)
+	}
+	aead, err := newAEAD(masterKey)
+	if err != nil {
+		return err
+	}
+	value, err := decryptEntry(aead, checkPath, data)
+	if err != nil || string(value) != checkValue {
+		return ErrInvalidKey
+	}
+	b.aead = aead
+	return nil
+}
+
+// Seal discards the master key and any pending unseal shares from memory;
+// storage operations fail until the next Unseal.
+func (b *Barrier) Seal() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.aead = nil
+	b.pendingShares = nil
+}
+
+// Get decrypts and returns the value stored at key.
+func (b *Barrier) Get(key string) ([]byte, error) {
+	aead, err := b.unsealedAEAD()
+	if err != nil {
+		return nil, err
+	}
+	data, err := b.backend.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	return decryptEntry(aead, key, data)
+}
+
+// Put encrypts value and stores it at key.
+func (b *Barrier) Put(key string, value []byte) error {
+	aead, err := b.unsealedAEAD()
+	if err != nil {
+		return err
+	}
+	data, err := encryptEntry(aead, key, value)
+	if err != nil {
+		return err
+	}
+	return b.backend.Put(key, data)
+}
+
+// Delete removes the entry at key.
+func (b *Barrier) Delete(key string) error {
+	if _, err := b.unsealedAEAD(); err != nil {
+		return err
+	}
+	return b.backend.Delete(key)
+}
+
+// List returns all keys starting with prefix. Key paths are not encrypted.
+func (b *Barrier) List(prefix string) ([]string, error) {
+	if _, err := b.unsealedAEAD(); err != nil {
+		return nil, err
+	}
+	return b.backend.List(prefix)
+}
+
+// unsealedAEAD returns the master-key AEAD, or ErrSealed.
+func (b *Barrier) unsealedAEAD() (cipher.AEAD, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.aead == nil {
+		return nil, ErrSealed
+	}
+	return b.aead, nil
+}
+
+// newAEAD creates an AES-256-GCM AEAD from the master key.
+func newAEAD(masterKey []byte) (cipher.AEAD, error) {
+	if len(masterKey) != KeySize {
+		return nil, ErrInvalidKey
+	}
+	block, err := aes.NewCipher(masterKey)
+	if err != nil {
+		return nil, fmt.Errorf("barrier: failed to create AES cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("barrier: failed to create GCM: %w", err)
+	}
+	return aead, nil
+}
+
+// encryptEntry seals value for the given path as version(1) | nonce | ciphertext.
+func encryptEntry(aead cipher.AEAD, path string, value []byte) ([]byte, error) {
+	out := make([]byte, 1+aead.NonceSize(), 1+aead.NonceSize()+len(value)+aead.Overhead())
+	out[0] = entryVersion
+	if _, err := rand.Read(out[1:]); err != nil {
+		return nil, fmt.Errorf("barrier: failed to generate nonce: %w", err)
+	}
+	return aead.Seal(out, out[1:], value, []byte(path)), nil
+}
+
+// decryptEntry opens an entry produced by encryptEntry for the same path.
+func decryptEntry(aead cipher.AEAD, path string, data []byte) ([]byte, error) {
+	if len(data) < 1+aead.NonceSize()+aead.Overhead() || data[0] != entryVersion {
+		return nil, fmt.Errorf("barrier: malformed entry %q", path)
+	}
+	nonce, sealed := data[1:1+aead.NonceSize()], data[1+aead.NonceSize():]
+	value, err := aead.Open(nil, nonce, sealed, []byte(path))
+	if err != nil {
+		return nil, fmt.Errorf("barrier: failed to decrypt entry %q: %w", path, err)
+	}
+	return value, nil
+}
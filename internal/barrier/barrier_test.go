@@ -0,0 +1,88 @@
+package barrier
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/dezween/ElevexaCodingChallenge2/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBarrier_Lifecycle(t *testing.T) {
+	backend := storage.NewMemory()
+	b := New(backend)
+	masterKey := bytes.Repeat([]byte{7}, KeySize)
+
+	assert.True(t, b.Sealed())
+	initialized, err := b.Initialized()
+	require.NoError(t, err)
+	assert.False(t, initialized)
+	assert.ErrorIs(t, b.Unseal(masterKey), ErrNotInitialized)
+
+	require.NoError(t, b.Initialize(masterKey))
+	assert.ErrorIs(t, b.Initialize(masterKey), ErrAlreadyInitialized)
+	assert.True(t, b.Sealed(), "initialize must not unseal")
+
+	_, err = b.Get("keys/a")
+	assert.ErrorIs(t, err, ErrSealed)
+	assert.ErrorIs(t, b.Put("keys/a", []byte("secret")), ErrSealed)
+	assert.ErrorIs(t, b.Delete("keys/a"), ErrSealed)
+	_, err = b.List("keys/")
+	assert.ErrorIs(t, err, ErrSealed)
+
+	require.NoError(t, b.Unseal(masterKey))
+	assert.False(t, b.Sealed())
+	require.NoError(t, b.Put("keys/a", []byte("secret")))
+	value, err := b.Get("keys/a")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("secret"), value)
+
+	raw, err := backend.Get("keys/a")
+	require.NoError(t, err)
+	assert.NotContains(t, string(raw), "secret", "values must be encrypted at rest")
+
+	b.Seal()
+	assert.True(t, b.Sealed())
+	_, err = b.Get("keys/a")
+	assert.ErrorIs(t, err, ErrSealed)
+}
+
+func TestBarrier_UnsealErrors_TableDriven(t *testing.T) {
+	masterKey := bytes.Repeat([]byte{1}, KeySize)
+	b := New(storage.NewMemory())
+	require.NoError(t, b.Initialize(masterKey))
+
+	tests := []struct {
+		name string
+		key  []byte
+	}{
+		{"wrong key", bytes.Repeat([]byte{2}, KeySize)},
+		{"short key", []byte("short")},
+		{"empty key", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorIs(t, b.Unseal(tt.key), ErrInvalidKey)
+			assert.True(t, b.Sealed())
+		})
+	}
+}
+
+func TestBarrier_EntriesBoundToPath(t *testing.T) {
+	backend := storage.NewMemory()
+	b := New(backend)
+	masterKey := bytes.Repeat([]byte{3}, KeySize)
+	require.NoError(t, b.Initialize(masterKey))
+	require.NoError(t, b.Unseal(masterKey))
+	require.NoError(t, b.Put("keys/a", []byte("a")))
+
+	// Copy the encrypted entry of "a" over "b" directly in the backend.
+	raw, err := backend.Get("keys/a")
+	require.NoError(t, err)
+	require.NoError(t, backend.Put("keys/b", raw))
+
+	_, err = b.Get("keys/b")
+	assert.ErrorContains(t, err, "failed to decrypt")
+}
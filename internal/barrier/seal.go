@@ -0,0 +1,236 @@
+package barrier
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/dezween/ElevexaCodingChallenge2/internal/shamir"
+	"github.com/dezween/ElevexaCodingChallenge2/internal/storage"
+)
+
+// sealConfigPath is the storage path of the (unencrypted) seal configuration.
+const sealConfigPath = "core/seal-config"
+
+// ErrInvalidSealConfig is returned by Init for an unusable share/threshold combination.
+var ErrInvalidSealConfig = errors.New("barrier: invalid seal configuration")
+
+// SealConfig describes how the master key is split into unseal key shares.
+type SealConfig struct {
+	SecretShares    int `json:"secret_shares"`    // Number of shares generated
+	SecretThreshold int `json:"secret_threshold"` // Number of shares required to unseal
+}
+
+// Validate checks that the configuration can be used with Shamir secret sharing.
+// A single share is allowed only with threshold 1; it is then the master key itself.
+func (c SealConfig) Validate() error {
+	switch {
+	case c.SecretShares < 1 || c.SecretShares > 255:
+		return fmt.Errorf("%w: secret_shares must be between 1 and 255", ErrInvalidSealConfig)
+	case c.SecretThreshold < 1 || c.SecretThreshold > c.SecretShares:
+		return fmt.Errorf("%w: secret_threshold must be between 1 and secret_shares", ErrInvalidSealConfig)
+	case c.SecretShares > 1 && c.SecretThreshold == 1:
+		return fmt.Errorf("%w: secret_threshold must be greater than 1 when secret_shares is greater than 1", ErrInvalidSealConfig)
+	}
+	return nil
+}
+
+// SealStatus reports the seal state and unseal progress.
+type SealStatus struct {
+	Initialized bool `json:"initialized"`
+	Sealed      bool `json:"sealed"`
+	Threshold   int  `json:"t"`        // Shares required to unseal
+	Shares      int  `json:"n"`        // Shares generated at init
+	Progress    int  `json:"progress"` // Distinct shares submitted so far
+}
+
+// Init generates a random master key, initializes the barrier with it and returns
+// the master key split into unseal key shares. The master key itself is not stored.
+//
+// If setup is non-nil it runs with the barrier temporarily unsealed, so initial
+// entries (such as the root token) can be written; the barrier is sealed again afterwards.
+// If setup fails the initialization is rolled back, so Init can be retried.
+func (b *Barrier) Init(cfg SealConfig, setup func() error) ([][]byte, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	masterKey := make([]byte, KeySize)
+	if _, err := rand.Read(masterKey); err != nil {
+		return nil, fmt.Errorf("barrier: failed to generate master key: %w", err)
+	}
+	shares := [][]byte{masterKey}
+	if cfg.SecretShares > 1 {
+		var err error
+		if shares, err = shamir.Split(masterKey, cfg.SecretShares, cfg.SecretThreshold); err != nil {
+			return nil, fmt.Errorf("barrier: failed to split master key: %w", err)
+		}
+	}
+
+	if err := b.initWithConfig(cfg, masterKey); err != nil {
+		return nil, err
+	}
+	if setup != nil {
+		if err := b.Unseal(masterKey); err != nil {
+			return nil, errors.Join(err, b.rollbackInit())
+		}
+		err := setup()
+		b.Seal()
+		if err != nil {
+			return nil, errors.Join(fmt.Errorf("barrier: init setup failed: %w", err), b.rollbackInit())
+		}
+	}
+	return shares, nil
+}
+
+// rollbackInit removes the check entry and then the seal config written by
+// initWithConfig, leaving the barrier uninitialized.
+func (b *Barrier) rollbackInit() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.backend.Delete(checkPath); err != nil {
+		return fmt.Errorf("barrier: failed to roll back init: %w", err)
+	}
+	if err := b.backend.Delete(sealConfigPath); err != nil {
+		return fmt.Errorf("barrier: failed to roll back init: %w", err)
+	}
+	return nil
+}
+
+// initWithConfig stores the seal config and the check entry for masterKey.
+func (b *Barrier) initWithConfig(cfg SealConfig, masterKey []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	initialized, err := b.Initialized()
+	if err != nil {
+		return err
+	}
+	if initialized {
+		return ErrAlreadyInitialized
+	}
+	// The seal config is written before the check entry, so a crash in between
+	// leaves the barrier uninitialized and Init can simply be retried.
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return fmt.Errorf("barrier: failed to encode seal config: %w", err)
+	}
+	if err := b.backend.Put(sealConfigPath, data); err != nil {
+		return fmt.Errorf("barrier: failed to write seal config: %w", err)
+	}
+	return b.initializeLocked(masterKey)
+}
+
+// SealConfig returns the stored seal configuration, or ErrNotInitialized.
+// A barrier initialized directly with Initialize has no stored configuration and
+// reports a single share (the master key itself).
+func (b *Barrier) SealConfig() (SealConfig, error) {
+	data, err := b.backend.Get(sealConfigPath)
+	if errors.Is(err, storage.ErrNotFound) {
+		initialized, err := b.Initialized()
+		if err != nil {
+			return SealConfig{}, err
+		}
+		if !initialized {
+			return SealConfig{}, ErrNotInitialized
+		}
+		return SealConfig{SecretShares: 1, SecretThreshold: 1}, nil
+	}
+	if err != nil {
+		return SealConfig{}, fmt.Errorf("barrier: failed to read seal config: %w", err)
+	}
+	var cfg SealConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return SealConfig{}, fmt.Errorf("barrier: failed to decode seal config: %w", err)
+	}
+	return cfg, nil
+}
+
+// Status returns the current seal status including unseal progress.
+func (b *Barrier) Status() (SealStatus, error) {
+	initialized, err := b.Initialized()
+	if err != nil {
+		return SealStatus{}, err
+	}
+	if !initialized {
+		return SealStatus{Sealed: true}, nil
+	}
+	cfg, err := b.SealConfig()
+	if err != nil {
+		return SealStatus{}, err
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return SealStatus{
+		Initialized: true,
+		Sealed:      b.aead == nil,
+		Threshold:   cfg.SecretThreshold,
+		Shares:      cfg.SecretShares,
+		Progress:    len(b.pendingShares),
+	}, nil
+}
+
+// SubmitUnsealKey adds one unseal key share. Once the threshold is reached the
+// master key is reconstructed and the barrier unsealed. Resubmitting a share that
+// is already pending does not advance progress. If the reconstructed key is wrong,
+// progress is reset and ErrInvalidKey is returned.
+func (b *Barrier) SubmitUnsealKey(share []byte) (SealStatus, error) {
+	status, err := b.Status()
+	if err != nil {
+		return SealStatus{}, err
+	}
+	if !status.Initialized {
+		return SealStatus{}, ErrNotInitialized
+	}
+	cfg, err := b.SealConfig()
+	if err != nil {
+		return SealStatus{}, err
+	}
+	if !status.Sealed {
+		return status, nil
+	}
+	wantLen := KeySize
+	if cfg.SecretShares > 1 {
+		wantLen += shamir.ShareOverhead
+	}
+	if len(share) != wantLen {
+		return SealStatus{}, ErrInvalidKey
+	}
+
+	b.mu.Lock()
+	duplicate := false
+	for _, p := range b.pendingShares {
+		if bytes.Equal(p, share) {
+			duplicate = true
+			break
+		}
+	}
+	if !duplicate {
+		b.pendingShares = append(b.pendingShares, append([]byte(nil), share...))
+	}
+	if len(b.pendingShares) < cfg.SecretThreshold {
+		b.mu.Unlock()
+		return b.Status()
+	}
+	pending := b.pendingShares
+	b.pendingShares = nil
+	b.mu.Unlock()
+
+	masterKey := pending[0]
+	if len(pending) > 1 {
+		if masterKey, err = shamir.Combine(pending); err != nil {
+			return SealStatus{}, ErrInvalidKey
+		}
+	}
+	if err := b.Unseal(masterKey); err != nil {
+		return SealStatus{}, err
+	}
+	return b.Status()
+}
+
+// ResetUnsealProgress discards all pending unseal key shares.
+func (b *Barrier) ResetUnsealProgress() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.pendingShares = nil
+}
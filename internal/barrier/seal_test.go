@@ -0,0 +1,172 @@
+package barrier
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/dezween/ElevexaCodingChallenge2/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBarrier_InitAndUnsealWithShares(t *testing.T) {
+	backend := storage.NewMemory()
+	b := New(backend)
+
+	status, err := b.Status()
+	require.NoError(t, err)
+	assert.Equal(t, SealStatus{Sealed: true}, status)
+	_, err = b.SubmitUnsealKey(bytes.Repeat([]byte{1}, KeySize))
+	assert.ErrorIs(t, err, ErrNotInitialized)
+
+	shares, err := b.Init(SealConfig{SecretShares: 5, SecretThreshold: 3}, nil)
+	require.NoError(t, err)
+	require.Len(t, shares, 5)
+	_, err = b.Init(SealConfig{SecretShares: 5, SecretThreshold: 3}, nil)
+	assert.ErrorIs(t, err, ErrAlreadyInitialized)
+
+	status, err = b.SubmitUnsealKey(shares[4])
+	require.NoError(t, err)
+	assert.Equal(t, SealStatus{Initialized: true, Sealed: true, Threshold: 3, Shares: 5, Progress: 1}, status)
+
+	status, err = b.SubmitUnsealKey(shares[4])
+	require.NoError(t, err)
+	assert.Equal(t, 1, status.Progress, "a repeated share must not advance progress")
+
+	status, err = b.SubmitUnsealKey(shares[1])
+	require.NoError(t, err)
+	assert.Equal(t, 2, status.Progress)
+	assert.True(t, status.Sealed)
+
+	status, err = b.SubmitUnsealKey(shares[2])
+	require.NoError(t, err)
+	assert.False(t, status.Sealed)
+	assert.Equal(t, 0, status.Progress)
+	require.NoError(t, b.Put("keys/a", []byte("secret")))
+
+	// After a restart any other three shares unseal as well.
+	b = New(backend)
+	for _, share := range [][]byte{shares[0], shares[3], shares[1]} {
+		status, err = b.SubmitUnsealKey(share)
+		require.NoError(t, err)
+	}
+	assert.False(t, status.Sealed)
+	value, err := b.Get("keys/a")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("secret"), value)
+}
+
+func TestBarrier_UnsealWithWrongShares(t *testing.T) {
+	b := New(storage.NewMemory())
+	shares, err := b.Init(SealConfig{SecretShares: 3, SecretThreshold: 2}, nil)
+	require.NoError(t, err)
+
+	forged := append([]byte(nil), shares[0]...)
+	forged[0] ^= 0xff
+	_, err = b.SubmitUnsealKey(forged)
+	require.NoError(t, err)
+	_, err = b.SubmitUnsealKey(shares[1])
+	assert.ErrorIs(t, err, ErrInvalidKey)
+
+	status, err := b.Status()
+	require.NoError(t, err)
+	assert.True(t, status.Sealed)
+	assert.Equal(t, 0, status.Progress, "progress resets after a failed attempt")
+
+	_, err = b.SubmitUnsealKey([]byte("short"))
+	assert.ErrorIs(t, err, ErrInvalidKey)
+
+	_, err = b.SubmitUnsealKey(shares[0])
+	require.NoError(t, err)
+	b.ResetUnsealProgress()
+	status, err = b.Status()
+	require.NoError(t, err)
+	assert.Equal(t, 0, status.Progress)
+}
+
+func TestBarrier_SingleShare(t *testing.T) {
+	b := New(storage.NewMemory())
+	var sealedDuringSetup bool
+	shares, err := b.Init(SealConfig{SecretShares: 1, SecretThreshold: 1}, func() error {
+		sealedDuringSetup = b.Sealed()
+		return b.Put("auth/root", []byte("written during setup"))
+	})
+	require.NoError(t, err)
+	assert.False(t, sealedDuringSetup, "setup runs unsealed")
+	assert.True(t, b.Sealed(), "barrier is sealed again after init")
+	require.Len(t, shares, 1)
+	assert.Len(t, shares[0], KeySize, "a single share is the master key itself")
+
+	status, err := b.SubmitUnsealKey(shares[0])
+	require.NoError(t, err)
+	assert.False(t, status.Sealed)
+	value, err := b.Get("auth/root")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("written during setup"), value)
+}
+
+func TestBarrier_InitSetupFailureRollsBack(t *testing.T) {
+	b := New(storage.NewMemory())
+	_, err := b.Init(SealConfig{SecretShares: 3, SecretThreshold: 2}, func() error {
+		return errors.New("root token not written")
+	})
+	require.ErrorContains(t, err, "root token not written")
+	initialized, err := b.Initialized()
+	require.NoError(t, err)
+	assert.False(t, initialized, "a failed setup must leave the barrier uninitialized")
+	_, err = b.SealConfig()
+	assert.ErrorIs(t, err, ErrNotInitialized)
+	assert.True(t, b.Sealed())
+
+	shares, err := b.Init(SealConfig{SecretShares: 3, SecretThreshold: 2}, func() error {
+		return b.Put("auth/root", []byte("written on retry"))
+	})
+	require.NoError(t, err, "Init must succeed when retried")
+	for _, share := range shares[:2] {
+		_, err = b.SubmitUnsealKey(share)
+		require.NoError(t, err)
+	}
+	value, err := b.Get("auth/root")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("written on retry"), value)
+}
+
+func TestBarrier_InitializedWithoutSealConfig(t *testing.T) {
+	b := New(storage.NewMemory())
+	masterKey := bytes.Repeat([]byte{4}, KeySize)
+	require.NoError(t, b.Initialize(masterKey))
+
+	cfg, err := b.SealConfig()
+	require.NoError(t, err)
+	assert.Equal(t, SealConfig{SecretShares: 1, SecretThreshold: 1}, cfg)
+	status, err := b.SubmitUnsealKey(masterKey)
+	require.NoError(t, err)
+	assert.False(t, status.Sealed)
+}
+
+func TestSealConfig_Validate_TableDriven(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     SealConfig
+		wantErr bool
+	}{
+		{"single share", SealConfig{1, 1}, false},
+		{"five of three", SealConfig{5, 3}, false},
+		{"zero shares", SealConfig{0, 0}, true},
+		{"too many shares", SealConfig{256, 2}, true},
+		{"threshold above shares", SealConfig{3, 4}, true},
+		{"threshold one with many shares", SealConfig{3, 1}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.Validate()
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidSealConfig)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
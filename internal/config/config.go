@@ -3,17 +3,37 @@ package config
 import (
 	"os"
 	"regexp"
+	"runtime"
+	"strconv"
+	"time"
 )
 
 // Config holds application configuration parameters.
 type Config struct {
-	Port string // HTTP server port, e.g. ":8080"
+	Port         string // HTTP server port, e.g. ":8080"
+	StoragePath  string // Directory for persistent key storage; empty means in-memory
+	AuditFile    string // Audit log file; empty disables the file sink
+	AuditStdout  bool   // Also write audit entries to stdout
+	BatchWorkers int    // Concurrent items per batch request
+
+	BatchMaxItems   int   // Maximum batch_input items per request
+	MaxRequestBytes int64 // Maximum request body size of the encrypt, decrypt and rewrap endpoints
+
+	RotationCheckInterval time.Duration // How often keys are checked for automatic rotation
 }
 
 var portPattern = regexp.MustCompile(`^:[0-9]{2,5}$`)
 
 // LoadConfig loads configuration from environment variables (with defaults).
 // Validates port format (":8080", ":9090", etc). Panics on invalid port.
+// KYBER_STORAGE_PATH selects a directory for persistent storage (default: in-memory).
+// KYBER_AUDIT_FILE and KYBER_AUDIT_STDOUT ("true"/"false") enable the audit sinks.
+// KYBER_BATCH_WORKERS limits concurrent batch_input items per request (default: number of CPUs).
+// KYBER_BATCH_MAX_ITEMS limits batch_input items per request (default: 1000).
+// KYBER_MAX_REQUEST_BYTES limits encrypt, decrypt and rewrap request bodies (default: 32 MiB).
+// KYBER_ROTATION_CHECK_INTERVAL sets how often keys are checked for auto rotation (default: 1m).
+// Panics on an invalid KYBER_AUDIT_STDOUT, KYBER_BATCH_WORKERS, KYBER_BATCH_MAX_ITEMS,
+// KYBER_MAX_REQUEST_BYTES or KYBER_ROTATION_CHECK_INTERVAL.
 func LoadConfig() *Config {
 	port := os.Getenv("KYBER_SERVER_PORT")
 	if port == "" {
@@ -24,7 +44,56 @@ func LoadConfig() *Config {
 	if !portPattern.MatchString(port) {
 		panic("Invalid port format: must be :PORT, e.g. :8080")
 	}
+	auditStdout := false
+	if v := os.Getenv("KYBER_AUDIT_STDOUT"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			panic("Invalid KYBER_AUDIT_STDOUT: must be true or false")
+		}
+		auditStdout = b
+	}
+	batchWorkers := runtime.NumCPU()
+	if v := os.Getenv("KYBER_BATCH_WORKERS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			panic("Invalid KYBER_BATCH_WORKERS: must be a positive integer")
+		}
+		batchWorkers = n
+	}
+	batchMaxItems := 1000
+	if v := os.Getenv("KYBER_BATCH_MAX_ITEMS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			panic("Invalid KYBER_BATCH_MAX_ITEMS: must be a positive integer")
+		}
+		batchMaxItems = n
+	}
+	var maxRequestBytes int64 = 32 << 20
+	if v := os.Getenv("KYBER_MAX_REQUEST_BYTES"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 1 {
+			panic("Invalid KYBER_MAX_REQUEST_BYTES: must be a positive integer")
+		}
+		maxRequestBytes = n
+	}
+	rotationCheckInterval := time.Minute
+	if v := os.Getenv("KYBER_ROTATION_CHECK_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			panic("Invalid KYBER_ROTATION_CHECK_INTERVAL: must be a positive duration, e.g. 1m")
+		}
+		rotationCheckInterval = d
+	}
 	return &Config{
-		Port: port,
+		Port:         port,
+		StoragePath:  os.Getenv("KYBER_STORAGE_PATH"),
+		AuditFile:    os.Getenv("KYBER_AUDIT_FILE"),
+		AuditStdout:  auditStdout,
+		BatchWorkers: batchWorkers,
+
+		BatchMaxItems:   batchMaxItems,
+		MaxRequestBytes: maxRequestBytes,
+
+		RotationCheckInterval: rotationCheckInterval,
 	}
 }
@@ -2,7 +2,9 @@ package config
 
 import (
 	"os"
+	"runtime"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -49,3 +51,121 @@ func TestLoadConfig_TableDriven(t *testing.T) {
 		})
 	}
 }
+
+func TestLoadConfig_StoragePath(t *testing.T) {
+	t.Setenv("KYBER_STORAGE_PATH", "")
+	assert.Equal(t, "", LoadConfig().StoragePath)
+
+	t.Setenv("KYBER_STORAGE_PATH", "/var/lib/kyber")
+	assert.Equal(t, "/var/lib/kyber", LoadConfig().StoragePath)
+}
+
+func TestLoadConfig_Audit(t *testing.T) {
+	tests := []struct {
+		name       string
+		file       string
+		stdout     string
+		wantFile   string
+		wantStdout bool
+		wantPanic  bool
+	}{
+		{"disabled", "", "", "", false, false},
+		{"file and stdout", "/var/log/kyber/audit.log", "true", "/var/log/kyber/audit.log", true, false},
+		{"stdout off", "", "false", "", false, false},
+		{"invalid stdout", "", "maybe", "", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("KYBER_AUDIT_FILE", tt.file)
+			t.Setenv("KYBER_AUDIT_STDOUT", tt.stdout)
+			if tt.wantPanic {
+				assert.Panics(t, func() { LoadConfig() })
+				return
+			}
+			cfg := LoadConfig()
+			assert.Equal(t, tt.wantFile, cfg.AuditFile)
+			assert.Equal(t, tt.wantStdout, cfg.AuditStdout)
+		})
+	}
+}
+
+func TestLoadConfig_BatchWorkers(t *testing.T) {
+	tests := []struct {
+		name      string
+		value     string
+		want      int
+		wantPanic bool
+	}{
+		{"default", "", runtime.NumCPU(), false},
+		{"explicit", "4", 4, false},
+		{"zero", "0", 0, true},
+		{"not a number", "many", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("KYBER_BATCH_WORKERS", tt.value)
+			if tt.wantPanic {
+				assert.Panics(t, func() { LoadConfig() })
+				return
+			}
+			assert.Equal(t, tt.want, LoadConfig().BatchWorkers)
+		})
+	}
+}
+
+func TestLoadConfig_BatchLimits(t *testing.T) {
+	tests := []struct {
+		name      string
+		maxItems  string
+		maxBytes  string
+		wantItems int
+		wantBytes int64
+		wantPanic bool
+	}{
+		{"default", "", "", 1000, 32 << 20, false},
+		{"explicit", "50", "1048576", 50, 1 << 20, false},
+		{"zero items", "0", "", 0, 0, true},
+		{"invalid bytes", "", "lots", 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("KYBER_BATCH_MAX_ITEMS", tt.maxItems)
+			t.Setenv("KYBER_MAX_REQUEST_BYTES", tt.maxBytes)
+			if tt.wantPanic {
+				assert.Panics(t, func() { LoadConfig() })
+				return
+			}
+			cfg := LoadConfig()
+			assert.Equal(t, tt.wantItems, cfg.BatchMaxItems)
+			assert.Equal(t, tt.wantBytes, cfg.MaxRequestBytes)
+		})
+	}
+}
+
+func TestLoadConfig_RotationCheckInterval(t *testing.T) {
+	tests := []struct {
+		name      string
+		value     string
+		want      time.Duration
+		wantPanic bool
+	}{
+		{"default", "", time.Minute, false},
+		{"explicit", "30s", 30 * time.Second, false},
+		{"zero", "0s", 0, true},
+		{"not a duration", "often", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("KYBER_ROTATION_CHECK_INTERVAL", tt.value)
+			if tt.wantPanic {
+				assert.Panics(t, func() { LoadConfig() })
+				return
+			}
+			assert.Equal(t, tt.want, LoadConfig().RotationCheckInterval)
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/dezween/ElevexaCodingChallenge2/internal/audit"
+	"github.com/gorilla/mux"
+)
+
+// auditLog receives one entry per authenticated API request; nil disables auditing.
+var auditLog *audit.Logger
+
+// UseAudit makes the handlers record API requests to the given sinks. The HMAC key
+// for sensitive fields is kept behind the barrier. Must be called after UseStorage
+// and before the router starts serving requests.
+func UseAudit(sinks ...audit.Sink) {
+	if len(sinks) == 0 {
+		auditLog = nil
+		return
+	}
+	auditLog = audit.NewLogger(func() ([]byte, error) {
+		return audit.LoadOrCreateHMACKey(keyBarrier)
+	}, sinks...)
+}
+
+// auditRecord returns the audit record of the request, if it is being audited.
+func auditRecord(r *http.Request) *audit.Request {
+	rec, _ := r.Context().Value(auditRecordContextKey).(*audit.Request)
+	return rec
+}
+
+// setAuditKeyVersion records the key version a handler used.
+func setAuditKeyVersion(r *http.Request, version int) {
+	if rec := auditRecord(r); rec != nil {
+		rec.KeyVersion = version
+	}
+}
+
+// bufferedResponse holds a response back until it has been audited.
+type bufferedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (b *bufferedResponse) Header() http.Header { return b.header }
+
+func (b *bufferedResponse) Write(p []byte) (int, error) {
+	if b.status == 0 {
+		b.status = http.StatusOK
+	}
+	return b.body.Write(p)
+}
+
+func (b *bufferedResponse) WriteHeader(status int) {
+	if b.status == 0 {
+		b.status = status
+	}
+}
+
+// Audit is middleware that writes an audit entry for every request it wraps.
+// The response is held back until the entry is written; if auditing fails the
+// client gets 500 instead, so no result leaves the server unrecorded.
+func Audit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logger := auditLog
+		if logger == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		rec := &audit.Request{
+			ID:       newRequestID(),
+			Method:   r.Method,
+			Path:     r.URL.Path,
+			KeyName:  mux.Vars(r)["name"],
+			ClientIP: clientIP(r),
+		}
+		if route := mux.CurrentRoute(r); route != nil {
+			rec.Route = route.GetName()
+		}
+		buf := &bufferedResponse{header: w.Header()}
+		next.ServeHTTP(buf, r.WithContext(context.WithValue(r.Context(), auditRecordContextKey, rec)))
+		if buf.status == 0 {
+			buf.status = http.StatusOK
+		}
+
+		err := logger.Log(audit.Entry{
+			Time:     time.Now().UTC(),
+			Request:  *rec,
+			Response: auditResponse(buf),
+		})
+		if err != nil {
+			log.Printf("[ERROR] audit failed, withholding response: %v", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Internal error"})
+			return
+		}
+		w.WriteHeader(buf.status)
+		if _, err := w.Write(buf.body.Bytes()); err != nil {
+			log.Printf("[ERROR] failed to write response: %v", err)
+		}
+	})
+}
+
+// auditResponse summarizes a buffered response. Only the error message is taken
+// from the body; plaintexts and ciphertexts are never recorded.
+func auditResponse(buf *bufferedResponse) audit.Response {
+	resp := audit.Response{Status: buf.status, Result: "success"}
+	switch {
+	case buf.status == http.StatusUnauthorized || buf.status == http.StatusForbidden:
+		resp.Result = "denied"
+	case buf.status >= http.StatusBadRequest:
+		resp.Result = "error"
+	}
+	if buf.status >= http.StatusBadRequest {
+		var body struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(buf.body.Bytes(), &body) == nil {
+			resp.Error = body.Error
+		}
+	}
+	return resp
+}
+
+// newRequestID returns a random hex request ID.
+func newRequestID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		log.Printf("[ERROR] failed to generate request ID: %v", err)
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// clientIP returns the host part of the request's remote address.
+// Forwarding headers are ignored since they are client-controlled.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,148 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/dezween/ElevexaCodingChallenge2/internal/audit"
+	"github.com/dezween/ElevexaCodingChallenge2/internal/handlers"
+	"github.com/dezween/ElevexaCodingChallenge2/internal/routes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingSink rejects every entry.
+type failingSink struct{}
+
+func (failingSink) Write([]byte) error { return errors.New("disk full") }
+
+func TestAudit_RecordsTransitOperations(t *testing.T) {
+	r := newTestRouter(t)
+	var buf bytes.Buffer
+	handlers.UseAudit(audit.NewWriter(&buf))
+	t.Cleanup(func() { handlers.UseAudit() })
+
+	keyURL, _ := r.Get(routes.RouteNameCreateKey).URL("name", testKey1)
+	encURL, _ := r.Get(routes.RouteNameEncrypt).URL("name", testKey1)
+	decURL, _ := r.Get(routes.RouteNameDecrypt).URL("name", testKey1)
+	lookupURL, _ := r.Get(routes.RouteNameTokenLookupSelf).URL()
+	_, self := doJSON(t, r, "GET", lookupURL.String(), nil)
+
+	doJSON(t, r, "POST", keyURL.String(), nil)
+	_, enc := doJSON(t, r, "POST", encURL.String(), map[string]string{"plaintext": b64("top secret")})
+	doJSON(t, r, "POST", decURL.String(), map[string]interface{}{"ciphertext": enc["ciphertext"]})
+	doJSONWithToken(t, r, "kt.invalid", "POST", decURL.String(), map[string]interface{}{"ciphertext": enc["ciphertext"]})
+
+	log := buf.String()
+	count, err := audit.Verify(strings.NewReader(log))
+	require.NoError(t, err)
+	require.Equal(t, 5, count)
+	assert.NotContains(t, log, b64("top secret"))
+	assert.NotContains(t, log, enc["ciphertext"])
+	assert.NotContains(t, log, self["accessor"], "accessor must be HMAC'd")
+	assert.NotContains(t, log, r.rootToken)
+
+	var entries []audit.Entry
+	for _, line := range strings.Split(strings.TrimSpace(log), "\n") {
+		var e audit.Entry
+		require.NoError(t, json.Unmarshal([]byte(line), &e))
+		entries = append(entries, e)
+	}
+
+	tests := []struct {
+		name        string
+		entry       audit.Entry
+		wantRoute   string
+		wantVersion int
+		wantStatus  int
+		wantResult  string
+		wantAuth    bool
+	}{
+		{"create key", entries[1], routes.RouteNameCreateKey, 1, http.StatusCreated, "success", true},
+		{"encrypt", entries[2], routes.RouteNameEncrypt, 1, http.StatusOK, "success", true},
+		{"decrypt", entries[3], routes.RouteNameDecrypt, 1, http.StatusOK, "success", true},
+		{"bad token", entries[4], routes.RouteNameDecrypt, 0, http.StatusUnauthorized, "denied", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wantRoute, tt.entry.Request.Route)
+			assert.Equal(t, testKey1, tt.entry.Request.KeyName)
+			assert.Equal(t, tt.wantVersion, tt.entry.Request.KeyVersion)
+			assert.Equal(t, tt.wantStatus, tt.entry.Response.Status)
+			assert.Equal(t, tt.wantResult, tt.entry.Response.Result)
+			assert.NotEmpty(t, tt.entry.Request.ID)
+			assert.NotEmpty(t, tt.entry.Request.ClientIP)
+			if tt.wantAuth {
+				assert.True(t, strings.HasPrefix(tt.entry.Request.Accessor, "hmac-sha256:"))
+			} else {
+				assert.Empty(t, tt.entry.Request.Accessor)
+				assert.Equal(t, "Missing or invalid token", tt.entry.Response.Error)
+			}
+		})
+	}
+}
+
+func TestAudit_RecordsKeyVersion(t *testing.T) {
+	r := newTestRouter(t)
+	keyURL, _ := r.Get(routes.RouteNameCreateKey).URL("name", testKey1)
+	rotateURL, _ := r.Get(routes.RouteNameRotateKey).URL("name", testKey1)
+	encURL, _ := r.Get(routes.RouteNameEncrypt).URL("name", testKey1)
+	decURL, _ := r.Get(routes.RouteNameDecrypt).URL("name", testKey1)
+	doJSON(t, r, "POST", keyURL.String(), nil)
+	_, v1 := doJSON(t, r, "POST", encURL.String(), map[string]string{"plaintext": b64("one")})
+	doJSON(t, r, "POST", rotateURL.String(), nil)
+	_, v2 := doJSON(t, r, "POST", encURL.String(), map[string]string{"plaintext": b64("two")})
+
+	tests := []struct {
+		name        string
+		url         string
+		body        interface{}
+		wantStatus  int
+		wantVersion int
+	}{
+		{"encrypt with requested version", encURL.String(), map[string]interface{}{"plaintext": b64("x"), "key_version": 1}, http.StatusOK, 1},
+		{"encrypt with unknown version", encURL.String(), map[string]interface{}{"plaintext": b64("x"), "key_version": 99}, http.StatusBadRequest, 0},
+		{"batch encrypt with requested version", encURL.String(), map[string]interface{}{"batch_input": []map[string]interface{}{
+			{"plaintext": b64("x"), "key_version": 1}, {"plaintext": b64("y"), "key_version": 1},
+		}}, http.StatusOK, 1},
+		{"batch encrypt with unknown version", encURL.String(), map[string]interface{}{"batch_input": []map[string]interface{}{
+			{"plaintext": b64("x"), "key_version": 99},
+		}}, http.StatusOK, 0},
+		{"batch decrypt", decURL.String(), map[string]interface{}{"batch_input": []map[string]interface{}{
+			{"ciphertext": v2["ciphertext"]}, {"ciphertext": v1["ciphertext"]},
+		}}, http.StatusOK, 2},
+		{"batch decrypt of invalid ciphertexts", decURL.String(), map[string]interface{}{"batch_input": []map[string]interface{}{
+			{"ciphertext": "garbage"},
+		}}, http.StatusOK, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			handlers.UseAudit(audit.NewWriter(&buf))
+			t.Cleanup(func() { handlers.UseAudit() })
+			code, resp := doJSON(t, r, "POST", tt.url, tt.body)
+			require.Equal(t, tt.wantStatus, code, resp)
+			var e audit.Entry
+			require.NoError(t, json.Unmarshal(buf.Bytes(), &e))
+			assert.Equal(t, tt.wantVersion, e.Request.KeyVersion)
+		})
+	}
+}
+
+func TestAudit_FailureWithholdsResponse(t *testing.T) {
+	r := newTestRouter(t)
+	keyURL, _ := r.Get(routes.RouteNameCreateKey).URL("name", testKey1)
+	doJSON(t, r, "POST", keyURL.String(), nil)
+
+	handlers.UseAudit(failingSink{})
+	t.Cleanup(func() { handlers.UseAudit() })
+
+	encURL, _ := r.Get(routes.RouteNameEncrypt).URL("name", testKey1)
+	code, resp := doJSON(t, r, "POST", encURL.String(), map[string]string{"plaintext": b64("data")})
+	assert.Equal(t, http.StatusInternalServerError, code)
+	assert.Equal(t, map[string]interface{}{"error": "Internal error"}, resp)
+}
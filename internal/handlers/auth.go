@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dezween/ElevexaCodingChallenge2/internal/auth"
+	"github.com/dezween/ElevexaCodingChallenge2/internal/policy"
+)
+
+// defaultTokenTTL is used by TokenCreateHandler when no ttl is requested.
+const defaultTokenTTL = 768 * time.Hour
+
+// contextKey is the type of request context keys set by this package.
+type contextKey int
+
+const (
+	// tokenContextKey holds the authenticated request token (tokenInfo).
+	tokenContextKey contextKey = iota
+	// auditRecordContextKey holds the *audit.Request of the request being audited,
+	// so later middleware and handlers can fill in the token accessor and key version.
+	auditRecordContextKey
+)
+
+// tokenInfo is the authenticated token attached to a request context.
+type tokenInfo struct {
+	ID    string
+	Token auth.Token
+}
+
+// requestToken returns the authenticated token of the request, if any.
+func requestToken(r *http.Request) (tokenInfo, bool) {
+	info, ok := r.Context().Value(tokenContextKey).(tokenInfo)
+	return info, ok
+}
+
+// bearerToken extracts the token from an "Authorization: Bearer <token>" header.
+func bearerToken(r *http.Request) string {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// RequireToken is middleware that authenticates requests with a bearer token.
+// Requests without a valid, unexpired token are rejected with 401.
+func RequireToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := bearerToken(r)
+		if id == "" {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "Missing or invalid token"})
+			return
+		}
+		token, err := tokenStore.Lookup(id)
+		if errors.Is(err, auth.ErrTokenNotFound) {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "Missing or invalid token"})
+			return
+		}
+		if err != nil {
+			writeError(w, fmt.Errorf("token lookup failed: %w", err))
+			return
+		}
+		if rec := auditRecord(r); rec != nil {
+			rec.Accessor = token.Accessor
+		}
+		ctx := context.WithValue(r.Context(), tokenContextKey, tokenInfo{ID: id, Token: token})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// tokenResponse renders token metadata; the token ID is included only when non-empty.
+func tokenResponse(id string, token auth.Token) map[string]interface{} {
+	resp := map[string]interface{}{
+		"accessor":   token.Accessor,
+		"policies":   token.Policies,
+		"root":       token.Root,
+		"created_at": token.CreatedAt,
+		"ttl":        int(token.TTL(time.Now()).Seconds()),
+	}
+	if !token.ExpiresAt.IsZero() {
+		resp["expires_at"] = token.ExpiresAt
+	}
+	if id != "" {
+		resp["token"] = id
+	}
+	return resp
+}
+
+// TokenCreateHandler handles POST /auth/token/create.
+// Issues a new token with the requested ttl (Go duration, default 768h) and policies.
+// Without "policies" the new token inherits the caller's policies; a non-root caller
+// may only attach policies it holds itself. A child never outlives an expiring caller:
+// a longer ttl is rejected and the default is capped at the caller's remaining ttl.
+// Returns 200 with token and accessor, 400 on invalid ttl, 403 on policy escalation, 500 on internal error.
+func TokenCreateHandler(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+		return
+	}
+	var req struct {
+		TTL      string   `json:"ttl"`
+		Policies []string `json:"policies"`
+	}
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &req); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid JSON"})
+			return
+		}
+	}
+	ttl := defaultTokenTTL
+	if req.TTL != "" {
+		ttl, err = time.ParseDuration(req.TTL)
+		if err != nil || ttl <= 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid ttl: must be a positive duration such as \"1h\""})
+			return
+		}
+	}
+	caller, _ := requestToken(r)
+	if !caller.Token.ExpiresAt.IsZero() {
+		remaining := caller.Token.TTL(time.Now())
+		if req.TTL == "" {
+			ttl = min(ttl, remaining)
+		} else if ttl > remaining {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("Invalid ttl: exceeds the remaining ttl %s of the calling token", remaining.Truncate(time.Second))})
+			return
+		}
+	}
+	policies := req.Policies
+	if policies == nil {
+		policies = caller.Token.Policies
+	}
+	if !slices.Contains(caller.Token.Policies, policy.RootName) {
+		for _, p := range policies {
+			if !slices.Contains(caller.Token.Policies, p) {
+				permissionDenied(w)
+				return
+			}
+		}
+	}
+	id, token, err := tokenStore.Create(ttl, policies)
+	if err != nil {
+		writeError(w, fmt.Errorf("failed to create token: %w", err))
+		return
+	}
+	writeJSON(w, http.StatusOK, tokenResponse(id, token))
+}
+
+// TokenLookupSelfHandler handles GET /auth/token/lookup-self.
+// Returns metadata of the calling token (never the token ID).
+func TokenLookupSelfHandler(w http.ResponseWriter, r *http.Request) {
+	info, _ := requestToken(r)
+	writeJSON(w, http.StatusOK, tokenResponse("", info.Token))
+}
+
+// TokenRevokeHandler handles POST /auth/token/revoke.
+// Revokes the token given by "token" or "accessor".
+// Returns 204 on success, 400 on missing input, 404 if the token does not exist.
+func TokenRevokeHandler(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+		return
+	}
+	var req struct {
+		Token    string `json:"token"`
+		Accessor string `json:"accessor"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid JSON"})
+		return
+	}
+	switch {
+	case req.Token != "":
+		err = tokenStore.Revoke(req.Token)
+	case req.Accessor != "":
+		err = tokenStore.RevokeAccessor(req.Accessor)
+	default:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Missing token or accessor"})
+		return
+	}
+	if errors.Is(err, auth.ErrTokenNotFound) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "Token not found"})
+		return
+	}
+	if err != nil {
+		writeError(w, fmt.Errorf("failed to revoke token: %w", err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// TokenRevokeSelfHandler handles POST /auth/token/revoke-self.
+// Revokes the calling token. Returns 204 on success.
+func TokenRevokeSelfHandler(w http.ResponseWriter, r *http.Request) {
+	info, _ := requestToken(r)
+	if err := tokenStore.Revoke(info.ID); err != nil && !errors.Is(err, auth.ErrTokenNotFound) {
+		writeError(w, fmt.Errorf("failed to revoke token: %w", err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
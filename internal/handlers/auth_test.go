@@ -0,0 +1,151 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dezween/ElevexaCodingChallenge2/internal/routes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// doJSONWithToken is doJSON with an explicit bearer token.
+func doJSONWithToken(t *testing.T, r http.Handler, token, method, url string, body interface{}) (int, map[string]interface{}) {
+	t.Helper()
+	var bodyBytes []byte
+	if body != nil {
+		bodyBytes, _ = json.Marshal(body)
+	}
+	req := httptest.NewRequest(method, url, bytes.NewReader(bodyBytes))
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var resp map[string]interface{}
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	return w.Code, resp
+}
+
+func TestTokenHandlers(t *testing.T) {
+	r := newTestRouter(t)
+	createURL, _ := r.Get(routes.RouteNameTokenCreate).URL()
+	lookupURL, _ := r.Get(routes.RouteNameTokenLookupSelf).URL()
+	revokeURL, _ := r.Get(routes.RouteNameTokenRevoke).URL()
+	revokeSelfURL, _ := r.Get(routes.RouteNameTokenRevokeSelf).URL()
+	keyURL, _ := r.Get(routes.RouteNameCreateKey).URL("name", testKey1)
+
+	code, root := doJSON(t, r, "GET", lookupURL.String(), nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, true, root["root"])
+	assert.Equal(t, float64(0), root["ttl"])
+	assert.Nil(t, root["token"], "lookup must not echo the token")
+
+	tests := []struct {
+		name       string
+		body       interface{}
+		wantStatus int
+		wantError  string
+		wantTTL    float64
+	}{
+		{"default ttl", nil, http.StatusOK, "", 768 * 3600},
+		{"explicit ttl", map[string]string{"ttl": "1h"}, http.StatusOK, "", 3600},
+		{"invalid ttl", map[string]string{"ttl": "soon"}, http.StatusBadRequest, "Invalid ttl: must be a positive duration such as \"1h\"", 0},
+		{"negative ttl", map[string]string{"ttl": "-1h"}, http.StatusBadRequest, "Invalid ttl: must be a positive duration such as \"1h\"", 0},
+		{"invalid JSON", "notjson", http.StatusBadRequest, "Invalid JSON", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, resp := doJSON(t, r, "POST", createURL.String(), tt.body)
+			assert.Equal(t, tt.wantStatus, code)
+			if tt.wantError != "" {
+				assert.Equal(t, tt.wantError, resp["error"])
+				return
+			}
+			assert.NotEmpty(t, resp["token"])
+			assert.NotEmpty(t, resp["accessor"])
+			assert.InDelta(t, tt.wantTTL, resp["ttl"], 5)
+		})
+	}
+
+	// A child token authenticates until it revokes itself.
+	_, child := doJSON(t, r, "POST", createURL.String(), map[string]string{"ttl": "1h"})
+	childToken := child["token"].(string)
+	code, _ = doJSONWithToken(t, r, childToken, "POST", keyURL.String(), nil)
+	assert.Equal(t, http.StatusCreated, code)
+	code, _ = doJSONWithToken(t, r, childToken, "POST", revokeSelfURL.String(), nil)
+	assert.Equal(t, http.StatusNoContent, code)
+	code, resp := doJSONWithToken(t, r, childToken, "GET", lookupURL.String(), nil)
+	assert.Equal(t, http.StatusUnauthorized, code)
+	assert.Equal(t, "Missing or invalid token", resp["error"])
+
+	// Revocation by token and by accessor.
+	_, byToken := doJSON(t, r, "POST", createURL.String(), nil)
+	_, byAccessor := doJSON(t, r, "POST", createURL.String(), nil)
+	revokeTests := []struct {
+		name       string
+		body       map[string]interface{}
+		wantStatus int
+		wantError  string
+	}{
+		{"by token", map[string]interface{}{"token": byToken["token"]}, http.StatusNoContent, ""},
+		{"by accessor", map[string]interface{}{"accessor": byAccessor["accessor"]}, http.StatusNoContent, ""},
+		{"already revoked", map[string]interface{}{"token": byToken["token"]}, http.StatusNotFound, "Token not found"},
+		{"missing input", map[string]interface{}{}, http.StatusBadRequest, "Missing token or accessor"},
+	}
+	for _, tt := range revokeTests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, resp := doJSON(t, r, "POST", revokeURL.String(), tt.body)
+			assert.Equal(t, tt.wantStatus, code)
+			if tt.wantError != "" {
+				assert.Equal(t, tt.wantError, resp["error"])
+			}
+		})
+	}
+	code, _ = doJSONWithToken(t, r, byAccessor["token"].(string), "GET", lookupURL.String(), nil)
+	assert.Equal(t, http.StatusUnauthorized, code)
+}
+
+func TestTokenCreateHandler_ChildTTLCapped(t *testing.T) {
+	r := newTestRouter(t)
+	createURL, _ := r.Get(routes.RouteNameTokenCreate).URL()
+	lookupURL, _ := r.Get(routes.RouteNameTokenLookupSelf).URL()
+	_, parent := doJSON(t, r, "POST", createURL.String(), map[string]string{"ttl": "1m"})
+	parentToken := parent["token"].(string)
+
+	tests := []struct {
+		name       string
+		body       interface{}
+		wantStatus int
+		wantError  string
+		wantTTL    float64
+	}{
+		{"longer ttl", map[string]string{"ttl": "8760h"}, http.StatusBadRequest, "Invalid ttl: exceeds the remaining ttl", 0},
+		{"default ttl capped", nil, http.StatusOK, "", 60},
+		{"shorter ttl", map[string]string{"ttl": "30s"}, http.StatusOK, "", 30},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, resp := doJSONWithToken(t, r, parentToken, "POST", createURL.String(), tt.body)
+			require.Equal(t, tt.wantStatus, code, resp)
+			if tt.wantError != "" {
+				assert.Contains(t, resp["error"], tt.wantError)
+				return
+			}
+			assert.InDelta(t, tt.wantTTL, resp["ttl"], 2)
+			code, child := doJSONWithToken(t, r, resp["token"].(string), "GET", lookupURL.String(), nil)
+			require.Equal(t, http.StatusOK, code)
+			assert.LessOrEqual(t, child["ttl"], parent["ttl"], "child must not outlive its parent")
+		})
+	}
+}
+
+func TestInitHandler_ReturnsRootToken(t *testing.T) {
+	r := newTestRouter(t)
+	require.NotEmpty(t, r.rootToken)
+	lookupURL, _ := r.Get(routes.RouteNameTokenLookupSelf).URL()
+	code, resp := doJSONWithToken(t, r, r.rootToken, "GET", lookupURL.String(), nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, true, resp["root"])
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// BackupKeyHandler handles GET /transit/backup/{name}.
+// Returns a plaintext backup of the key: every version with its private key, and the
+// key configuration, as base64-encoded JSON that POST /transit/restore/{name} accepts.
+// The key must have both exportable and allow_plaintext_backup set.
+// Returns 200 on success, 400 if backups are not allowed, 404 if key not found.
+func BackupKeyHandler(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	key, err := keyStoreManager.GetKey(name)
+	if errors.Is(err, ErrKeyNotFound) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "Key not found"})
+		return
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if !key.Exportable || !key.AllowPlaintextBackup {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Plaintext backup is not allowed: set exportable and allow_plaintext_backup first"})
+		return
+	}
+	setAuditKeyVersion(r, key.LatestVersion())
+	data, err := json.Marshal(key)
+	if err != nil {
+		writeError(w, fmt.Errorf("failed to encode backup: %w", err))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"backup": base64.StdEncoding.EncodeToString(data)})
+}
+
+// RestoreKeyHandler handles POST /transit/restore/{name}.
+// Recreates a key from the "backup" returned by GET /transit/backup, with all of its
+// versions and configuration, under the given name, which may differ from the name
+// the backup was taken from.
+// Returns 201 on success, 400 on a malformed backup, 409 if key exists.
+func RestoreKeyHandler(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+		return
+	}
+	var req struct {
+		Backup string `json:"backup"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid JSON"})
+		return
+	}
+	if req.Backup == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Missing backup"})
+		return
+	}
+	data, err := base64.StdEncoding.DecodeString(req.Backup)
+	var backup Key
+	if err == nil {
+		err = json.Unmarshal(data, &backup)
+	}
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid backup: must be base64-encoded JSON from GET /transit/backup"})
+		return
+	}
+	key, exists, err := keyStoreManager.RestoreKey(name, backup)
+	if errors.Is(err, ErrInvalidKeyConfig) {
+		invalidKeyConfig(w, err)
+		return
+	}
+	if err != nil {
+		writeError(w, fmt.Errorf("failed to restore key: %w", err))
+		return
+	}
+	if exists {
+		writeJSON(w, http.StatusConflict, map[string]string{"error": "Key already exists"})
+		return
+	}
+	setAuditKeyVersion(r, key.LatestVersion())
+	writeJSON(w, http.StatusCreated, keyMetadata(key))
+}
@@ -0,0 +1,101 @@
+package handlers_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/dezween/ElevexaCodingChallenge2/internal/routes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackupRestore(t *testing.T) {
+	r := newTestRouter(t)
+	createURL, _ := r.Get(routes.RouteNameCreateKey).URL("name", testKey1)
+	rotateURL, _ := r.Get(routes.RouteNameRotateKey).URL("name", testKey1)
+	encURL, _ := r.Get(routes.RouteNameEncrypt).URL("name", testKey1)
+	backupURL, _ := r.Get(routes.RouteNameBackup).URL("name", testKey1)
+	restoreURL, _ := r.Get(routes.RouteNameRestore).URL("name", testKey3)
+	decURL, _ := r.Get(routes.RouteNameDecrypt).URL("name", testKey3)
+	code, resp := doJSON(t, r, "POST", createURL.String(), map[string]interface{}{
+		"type": "ml-kem-768", "exportable": true, "allow_plaintext_backup": true, "auto_rotate_period": "720h",
+	})
+	require.Equal(t, http.StatusCreated, code, resp)
+	doJSON(t, r, "POST", rotateURL.String(), nil)
+	code, enc := doJSON(t, r, "POST", encURL.String(), map[string]interface{}{"plaintext": b64("backed up"), "key_version": 1})
+	require.Equal(t, http.StatusOK, code, enc)
+
+	code, backup := doJSON(t, r, "GET", backupURL.String(), nil)
+	require.Equal(t, http.StatusOK, code, backup)
+	code, restored := doJSON(t, r, "POST", restoreURL.String(), map[string]interface{}{"backup": backup["backup"]})
+	require.Equal(t, http.StatusCreated, code, restored)
+	assert.Equal(t, testKey3, restored["name"])
+	assert.Equal(t, "ml-kem-768", restored["type"])
+	assert.Equal(t, float64(2), restored["latest_version"])
+	assert.Equal(t, true, restored["allow_plaintext_backup"])
+	assert.Equal(t, "720h0m0s", restored["auto_rotate_period"])
+
+	code, dec := doJSON(t, r, "POST", decURL.String(), map[string]interface{}{"ciphertext": enc["ciphertext"]})
+	require.Equal(t, http.StatusOK, code, dec)
+	assert.Equal(t, b64("backed up"), dec["plaintext"], "restored key decrypts ciphertexts of the original")
+
+	code, resp = doJSON(t, r, "POST", restoreURL.String(), map[string]interface{}{"backup": backup["backup"]})
+	assert.Equal(t, http.StatusConflict, code)
+	assert.Equal(t, "Key already exists", resp["error"])
+}
+
+func TestBackupRestore_Errors(t *testing.T) {
+	r := newTestRouter(t)
+	for name, config := range map[string]map[string]interface{}{
+		testKey1: {"exportable": true},
+		testKey2: {"allow_plaintext_backup": true},
+	} {
+		createURL, _ := r.Get(routes.RouteNameCreateKey).URL("name", name)
+		code, resp := doJSON(t, r, "POST", createURL.String(), config)
+		require.Equal(t, http.StatusCreated, code, resp)
+	}
+	encode := func(v interface{}) string {
+		data, err := json.Marshal(v)
+		require.NoError(t, err)
+		return base64.StdEncoding.EncodeToString(data)
+	}
+	keyPair := map[string]string{"public_key": b64("pub"), "private_key": b64("priv")}
+
+	tests := []struct {
+		name       string
+		method     string
+		url        string
+		body       interface{}
+		wantStatus int
+		wantError  string
+	}{
+		{"backup without allow_plaintext_backup", "GET", "/transit/backup/" + testKey1, nil, http.StatusBadRequest, "Plaintext backup is not allowed: set exportable and allow_plaintext_backup first"},
+		{"backup without exportable", "GET", "/transit/backup/" + testKey2, nil, http.StatusBadRequest, "Plaintext backup is not allowed: set exportable and allow_plaintext_backup first"},
+		{"backup of unknown key", "GET", "/transit/backup/" + unknownKey, nil, http.StatusNotFound, "Key not found"},
+		{"missing backup", "POST", "/transit/restore/" + testKey3, map[string]string{}, http.StatusBadRequest, "Missing backup"},
+		{"backup not base64", "POST", "/transit/restore/" + testKey3, map[string]string{"backup": "!!!"}, http.StatusBadRequest, "Invalid backup: must be base64-encoded JSON from GET /transit/backup"},
+		{"backup not JSON", "POST", "/transit/restore/" + testKey3, map[string]string{"backup": b64("backup")}, http.StatusBadRequest, "Invalid backup: must be base64-encoded JSON from GET /transit/backup"},
+		{"unknown key type", "POST", "/transit/restore/" + testKey3, map[string]string{"backup": encode(map[string]interface{}{
+			"type": "rsa-2048", "versions": []interface{}{map[string]interface{}{"version": 1, "key_pair": keyPair}},
+		})}, http.StatusBadRequest, `Invalid key config: unsupported key type "rsa-2048"`},
+		{"no versions", "POST", "/transit/restore/" + testKey3, map[string]string{"backup": encode(map[string]interface{}{"type": "ml-kem-768"})}, http.StatusBadRequest, "Invalid key config: key has no versions"},
+		{"versions out of order", "POST", "/transit/restore/" + testKey3, map[string]string{"backup": encode(map[string]interface{}{
+			"type": "ml-kem-768", "versions": []interface{}{map[string]interface{}{"version": 2, "key_pair": keyPair}, map[string]interface{}{"version": 1, "key_pair": keyPair}},
+		})}, http.StatusBadRequest, "Invalid key config: versions must be positive and ascending"},
+		{"missing key pair", "POST", "/transit/restore/" + testKey3, map[string]string{"backup": encode(map[string]interface{}{
+			"type": "ml-kem-768", "versions": []interface{}{map[string]interface{}{"version": 1}},
+		})}, http.StatusBadRequest, "Invalid key config: version 1 is missing its key pair"},
+		{"invalid config", "POST", "/transit/restore/" + testKey3, map[string]string{"backup": encode(map[string]interface{}{
+			"type": "ml-kem-768", "min_decryption_version": 2, "versions": []interface{}{map[string]interface{}{"version": 1, "key_pair": keyPair}},
+		})}, http.StatusBadRequest, "Invalid key config: min_decryption_version must be between 1 and the latest version 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, resp := doJSON(t, r, tt.method, tt.url, tt.body)
+			assert.Equal(t, tt.wantStatus, code)
+			assert.Equal(t, tt.wantError, resp["error"])
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"runtime"
+	"sync"
+)
+
+const (
+	// DefaultBatchMaxItems is the default limit on batch_input items per request.
+	DefaultBatchMaxItems = 1000
+	// DefaultMaxRequestBytes is the default body size limit of the batch-capable endpoints.
+	DefaultMaxRequestBytes = 32 << 20
+)
+
+var (
+	// batchWorkers limits how many items of one batch request are processed concurrently.
+	batchWorkers = runtime.NumCPU()
+	// batchMaxItems limits the number of batch_input items per request.
+	batchMaxItems = DefaultBatchMaxItems
+	// maxRequestBytes limits the request body of the batch-capable endpoints.
+	maxRequestBytes int64 = DefaultMaxRequestBytes
+)
+
+// SetBatchWorkers sets the per-request concurrency limit for batch_input items.
+// Values below 1 are treated as 1. Must be called before the router starts serving requests.
+func SetBatchWorkers(n int) {
+	batchWorkers = max(n, 1)
+}
+
+// SetBatchLimits sets the maximum number of batch_input items and the maximum request
+// body size in bytes of the encrypt, decrypt and rewrap endpoints. Values below 1 are
+// treated as 1. Must be called before the router starts serving requests.
+func SetBatchLimits(maxItems int, maxBytes int64) {
+	batchMaxItems = max(maxItems, 1)
+	maxRequestBytes = max(maxBytes, 1)
+}
+
+// readBatchBody reads the body of a batch-capable request, allowing at most
+// maxRequestBytes. A larger body is rejected with 413.
+func readBatchBody(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBytes))
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return nil, &apiError{
+			status:  http.StatusRequestEntityTooLarge,
+			message: fmt.Sprintf("Request body too large: at most %d bytes are allowed", tooLarge.Limit),
+		}
+	}
+	if err != nil {
+		return nil, badRequest("Invalid request body")
+	}
+	return body, nil
+}
+
+// checkBatchInput rejects empty or oversized batches and requests that mix batch_input
+// with a single item.
+func checkBatchInput(n int, hasSingle bool) error {
+	if hasSingle {
+		return badRequest("Use either batch_input or a single item, not both")
+	}
+	if n == 0 {
+		return badRequest("Empty batch_input")
+	}
+	if n > batchMaxItems {
+		return badRequest(fmt.Sprintf("Too many batch_input items: at most %d are allowed", batchMaxItems))
+	}
+	return nil
+}
+
+// runBatch calls fn for every index in [0, n), with at most batchWorkers calls running at once.
+func runBatch(n int, fn func(i int)) {
+	sem := make(chan struct{}, batchWorkers)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			fn(i)
+		}()
+	}
+	wg.Wait()
+}
+
+// batchResult renders the outcome of one batch item: resp on success, otherwise
+// {"error": message}. Like writeError, only *apiError messages reach the client.
+func batchResult(resp map[string]interface{}, err error) map[string]interface{} {
+	if err == nil {
+		return resp
+	}
+	var apiErr *apiError
+	if errors.As(err, &apiErr) {
+		return map[string]interface{}{"error": apiErr.message}
+	}
+	log.Printf("[ERROR] batch item failed: %v", err)
+	return map[string]interface{}{"error": "Internal error"}
+}
@@ -0,0 +1,120 @@
+package handlers_test
+
+import (
+	"net/http"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/dezween/ElevexaCodingChallenge2/internal/handlers"
+	"github.com/dezween/ElevexaCodingChallenge2/internal/routes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchEncryptDecrypt(t *testing.T) {
+	r := newTestRouter(t)
+	handlers.SetBatchWorkers(2)
+	t.Cleanup(func() { handlers.SetBatchWorkers(runtime.NumCPU()) })
+
+	keyURL, _ := r.Get(routes.RouteNameCreateKey).URL("name", testKey1)
+	encURL, _ := r.Get(routes.RouteNameEncrypt).URL("name", testKey1)
+	decURL, _ := r.Get(routes.RouteNameDecrypt).URL("name", testKey1)
+	doJSON(t, r, "POST", keyURL.String(), nil)
+
+	plaintexts := []string{"one", "two", "", "four", "five"}
+	var input []map[string]string
+	for _, p := range plaintexts {
+		input = append(input, map[string]string{"plaintext": p, "encoding": "text"})
+	}
+	code, resp := doJSON(t, r, "POST", encURL.String(), map[string]interface{}{"batch_input": input})
+	require.Equal(t, http.StatusOK, code)
+	encResults := resp["batch_results"].([]interface{})
+	require.Len(t, encResults, len(plaintexts))
+	assert.Equal(t, "Missing plaintext", encResults[2].(map[string]interface{})["error"])
+
+	var decInput []map[string]interface{}
+	for i, res := range encResults {
+		item := res.(map[string]interface{})
+		if i == 2 {
+			decInput = append(decInput, map[string]interface{}{"ciphertext": "kyber:v1:garbage"})
+			continue
+		}
+		assert.Equal(t, float64(1), item["key_version"])
+		decInput = append(decInput, map[string]interface{}{"ciphertext": item["ciphertext"], "encoding": "text"})
+	}
+	code, resp = doJSON(t, r, "POST", decURL.String(), map[string]interface{}{"batch_input": decInput})
+	require.Equal(t, http.StatusOK, code)
+	decResults := resp["batch_results"].([]interface{})
+	require.Len(t, decResults, len(plaintexts))
+	for i, res := range decResults {
+		item := res.(map[string]interface{})
+		if i == 2 {
+			assert.Equal(t, "Invalid ciphertext format", item["error"])
+			assert.Nil(t, item["plaintext"])
+			continue
+		}
+		assert.Equal(t, plaintexts[i], item["plaintext"], "results keep input order")
+		assert.Nil(t, item["error"])
+	}
+}
+
+func TestBatchInput_Errors_TableDriven(t *testing.T) {
+	r := newTestRouter(t)
+	keyURL, _ := r.Get(routes.RouteNameCreateKey).URL("name", testKey1)
+	doJSON(t, r, "POST", keyURL.String(), nil)
+
+	tests := []struct {
+		name      string
+		route     string
+		body      interface{}
+		wantError string
+	}{
+		{"empty encrypt batch", routes.RouteNameEncrypt, map[string]interface{}{"batch_input": []interface{}{}}, "Empty batch_input"},
+		{"empty decrypt batch", routes.RouteNameDecrypt, map[string]interface{}{"batch_input": []interface{}{}}, "Empty batch_input"},
+		{"encrypt mixed", routes.RouteNameEncrypt, map[string]interface{}{"plaintext": b64("x"), "batch_input": []interface{}{map[string]string{"plaintext": b64("y")}}}, "Use either batch_input or a single item, not both"},
+		{"decrypt mixed", routes.RouteNameDecrypt, map[string]interface{}{"ciphertext": "x", "batch_input": []interface{}{map[string]string{"ciphertext": "y"}}}, "Use either batch_input or a single item, not both"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			url, _ := r.Get(tt.route).URL("name", testKey1)
+			code, resp := doJSON(t, r, "POST", url.String(), tt.body)
+			assert.Equal(t, http.StatusBadRequest, code)
+			assert.Equal(t, tt.wantError, resp["error"])
+		})
+	}
+}
+
+func TestBatchLimits(t *testing.T) {
+	r := newTestRouter(t)
+	handlers.SetBatchLimits(2, 512)
+	t.Cleanup(func() { handlers.SetBatchLimits(handlers.DefaultBatchMaxItems, handlers.DefaultMaxRequestBytes) })
+	keyURL, _ := r.Get(routes.RouteNameCreateKey).URL("name", testKey1)
+	doJSON(t, r, "POST", keyURL.String(), nil)
+
+	item := map[string]string{"plaintext": b64("x")}
+	tests := []struct {
+		name       string
+		route      string
+		body       interface{}
+		wantStatus int
+		wantError  string
+	}{
+		{"at item limit", routes.RouteNameEncrypt, map[string]interface{}{"batch_input": []interface{}{item, item}}, http.StatusOK, ""},
+		{"too many encrypt items", routes.RouteNameEncrypt, map[string]interface{}{"batch_input": []interface{}{item, item, item}}, http.StatusBadRequest, "Too many batch_input items: at most 2 are allowed"},
+		{"too many decrypt items", routes.RouteNameDecrypt, map[string]interface{}{"batch_input": []interface{}{item, item, item}}, http.StatusBadRequest, "Too many batch_input items: at most 2 are allowed"},
+		{"too many rewrap items", routes.RouteNameRewrap, map[string]interface{}{"batch_input": []interface{}{item, item, item}}, http.StatusBadRequest, "Too many batch_input items: at most 2 are allowed"},
+		{"body too large", routes.RouteNameEncrypt, map[string]string{"plaintext": b64(strings.Repeat("x", 1024))}, http.StatusRequestEntityTooLarge, "Request body too large: at most 512 bytes are allowed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			url, _ := r.Get(tt.route).URL("name", testKey1)
+			code, resp := doJSON(t, r, "POST", url.String(), tt.body)
+			assert.Equal(t, tt.wantStatus, code)
+			if tt.wantError != "" {
+				assert.Equal(t, tt.wantError, resp["error"])
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// dataKeySize is the size of generated data keys (AES-256).
+const dataKeySize = 32
+
+// DataKeyHandler handles POST /transit/datakey/{plaintext|wrapped}/{name}.
+// Generates a random AES-256 data key and returns it wrapped under the latest version
+// of the named key as a "<type>:v<version>:<base64>" ciphertext. The "plaintext" variant
+// also returns the base64 data key itself; "wrapped" never lets it leave the server.
+// The wrapped key is unwrapped with POST /transit/decrypt/{name}, which returns the
+// data key base64-encoded, exactly as the "plaintext" variant does. Derived keys require
+// a base64 "context" in the request body, which decrypt must be given as well.
+// Returns 200 on success, 400 for non-encryption keys, 404 if key not found, 500 on error.
+func DataKeyHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	name := vars["name"]
+	key, err := keyStoreManager.GetKey(name)
+	if errors.Is(err, ErrKeyNotFound) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "Key not found"})
+		return
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if !key.Type.SupportsEncryption() {
+		writeError(w, unsupportedOperation(key, "encryption"))
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+		return
+	}
+	var req struct {
+		Context string `json:"context"`
+	}
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &req); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid JSON"})
+			return
+		}
+	}
+	context, err := keyContext(key, req.Context)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	dataKey := make([]byte, dataKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		writeError(w, fmt.Errorf("failed to generate data key: %w", err))
+		return
+	}
+	latest := key.Latest()
+	setAuditKeyVersion(r, latest.Version)
+	resp, err := sealPlaintext(key, latest, dataKey, nil, context)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if vars["type"] == "plaintext" {
+		resp["plaintext"] = base64.StdEncoding.EncodeToString(dataKey)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,61 @@
+package handlers_test
+
+import (
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/dezween/ElevexaCodingChallenge2/internal/routes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDataKeyHandler(t *testing.T) {
+	r := newTestRouter(t)
+	keyURL, _ := r.Get(routes.RouteNameCreateKey).URL("name", testKey1)
+	signingURL, _ := r.Get(routes.RouteNameCreateKey).URL("name", testKey2)
+	decURL, _ := r.Get(routes.RouteNameDecrypt).URL("name", testKey1)
+	doJSON(t, r, "POST", keyURL.String(), nil)
+	doJSON(t, r, "POST", signingURL.String(), map[string]string{"type": "ml-dsa-44"})
+
+	plainURL, _ := r.Get(routes.RouteNameDataKey).URL("type", "plaintext", "name", testKey1)
+	code, resp := doJSON(t, r, "POST", plainURL.String(), nil)
+	require.Equal(t, http.StatusOK, code, resp)
+	dataKey, err := base64.StdEncoding.DecodeString(resp["plaintext"].(string))
+	require.NoError(t, err)
+	assert.Len(t, dataKey, 32)
+	assert.Equal(t, float64(1), resp["key_version"])
+	assert.True(t, strings.HasPrefix(resp["ciphertext"].(string), "kyber:v1:"))
+
+	code, dec := doJSON(t, r, "POST", decURL.String(), map[string]interface{}{"ciphertext": resp["ciphertext"]})
+	require.Equal(t, http.StatusOK, code, dec)
+	assert.Equal(t, resp["plaintext"], dec["plaintext"])
+
+	wrappedURL, _ := r.Get(routes.RouteNameDataKey).URL("type", "wrapped", "name", testKey1)
+	code, wrapped := doJSON(t, r, "POST", wrappedURL.String(), nil)
+	require.Equal(t, http.StatusOK, code, wrapped)
+	assert.NotContains(t, wrapped, "plaintext")
+	code, dec = doJSON(t, r, "POST", decURL.String(), map[string]interface{}{"ciphertext": wrapped["ciphertext"]})
+	require.Equal(t, http.StatusOK, code, dec)
+	unwrapped, err := base64.StdEncoding.DecodeString(dec["plaintext"].(string))
+	require.NoError(t, err)
+	assert.Len(t, unwrapped, 32)
+	assert.NotEqual(t, dataKey, unwrapped)
+
+	tests := []struct {
+		name       string
+		url        string
+		wantStatus int
+	}{
+		{"unknown key", "/transit/datakey/plaintext/" + unknownKey, http.StatusNotFound},
+		{"signing key", "/transit/datakey/wrapped/" + testKey2, http.StatusBadRequest},
+		{"unknown variant", "/transit/datakey/raw/" + testKey1, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _ := doJSON(t, r, "POST", tt.url, nil)
+			assert.Equal(t, tt.wantStatus, code)
+		})
+	}
+}
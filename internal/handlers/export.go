@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/dezween/ElevexaCodingChallenge2/internal/kybertransit"
+	"github.com/gorilla/mux"
+)
+
+const (
+	// exportPublicKey is the {type} of GET /transit/export that returns public keys.
+	exportPublicKey = "public-key"
+	// exportPrivateKey is the {type} of GET /transit/export that returns private keys.
+	exportPrivateKey = "private-key"
+
+	// exportFormatPEM marks exported keys as PEM blocks: "PUBLIC KEY" (SubjectPublicKeyInfo)
+	// or "PRIVATE KEY" (PKCS #8) carrying the NIST ML-KEM or ML-DSA algorithm identifier.
+	exportFormatPEM = "pem"
+	// exportFormatRaw marks exported keys as the base64 CIRCL key bytes, used for key
+	// types without a standard encoding.
+	exportFormatRaw = "raw"
+)
+
+// ExportKeyHandler handles GET /transit/export/{public-key|private-key}/{name}[/{version}].
+// Returns the key material of every available version of the key, or only of the given
+// version, keyed by version number. ML-KEM and ML-DSA keys are PEM-encoded with their
+// standard algorithm identifiers ("format": "pem"); round-3 Kyber and X-Wing keys have
+// none and are returned as base64 raw bytes ("format": "raw"). Public keys can always
+// be exported; private keys only if the key is exportable.
+// Returns 200 on success, 400 if the key is not exportable or the version is invalid,
+// 404 if key or version not found.
+func ExportKeyHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	name, exportType := vars["name"], vars["type"]
+	key, err := keyStoreManager.GetKey(name)
+	if errors.Is(err, ErrKeyNotFound) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "Key not found"})
+		return
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if exportType == exportPrivateKey && !key.Exportable {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Key is not exportable: set exportable to export private keys"})
+		return
+	}
+	versions := key.Versions
+	if v, ok := vars["version"]; ok {
+		version, err := strconv.Atoi(v)
+		if err != nil || version < 1 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid key version"})
+			return
+		}
+		kv, ok := key.Version(version)
+		if !ok {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "Key version not found"})
+			return
+		}
+		setAuditKeyVersion(r, version)
+		versions = []KeyVersion{kv}
+	}
+	format := exportFormatRaw
+	if key.Type.HasStandardEncoding() {
+		format = exportFormatPEM
+	}
+	keys := make(map[string]string, len(versions))
+	for _, kv := range versions {
+		encoded, err := encodeExportedKey(key.Type, exportType, format, kv.KeyPair)
+		if err != nil {
+			writeError(w, fmt.Errorf("failed to encode key version %d: %w", kv.Version, err))
+			return
+		}
+		keys[strconv.Itoa(kv.Version)] = encoded
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"name":        key.Name,
+		"type":        key.Type,
+		"export_type": exportType,
+		"format":      format,
+		"keys":        keys,
+	})
+}
+
+// encodeExportedKey encodes the public or private key of a key pair in the given format.
+func encodeExportedKey(keyType kybertransit.KeyType, exportType, format string, kp kybertransit.KeyPair) (string, error) {
+	if format == exportFormatRaw {
+		if exportType == exportPrivateKey {
+			return base64.StdEncoding.EncodeToString(kp.PrivateKey), nil
+		}
+		return base64.StdEncoding.EncodeToString(kp.PublicKey), nil
+	}
+	if exportType == exportPrivateKey {
+		der, err := kybertransit.MarshalPKCS8PrivateKey(keyType, kp.PrivateKey)
+		if err != nil {
+			return "", err
+		}
+		return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})), nil
+	}
+	der, err := kybertransit.MarshalPKIXPublicKey(keyType, kp.PublicKey)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})), nil
+}
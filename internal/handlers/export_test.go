@@ -0,0 +1,95 @@
+package handlers_test
+
+import (
+	"encoding/base64"
+	"encoding/pem"
+	"net/http"
+	"testing"
+
+	"github.com/dezween/ElevexaCodingChallenge2/internal/kybertransit"
+	"github.com/dezween/ElevexaCodingChallenge2/internal/routes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportKeyHandler(t *testing.T) {
+	r := newTestRouter(t)
+	exportableURL, _ := r.Get(routes.RouteNameCreateKey).URL("name", testKey1)
+	plainURL, _ := r.Get(routes.RouteNameCreateKey).URL("name", testKey2)
+	rotateURL, _ := r.Get(routes.RouteNameRotateKey).URL("name", testKey1)
+	encURL, _ := r.Get(routes.RouteNameEncrypt).URL("name", testKey1)
+	code, resp := doJSON(t, r, "POST", exportableURL.String(), map[string]interface{}{"type": "ml-kem-768", "exportable": true})
+	require.Equal(t, http.StatusCreated, code, resp)
+	doJSON(t, r, "POST", plainURL.String(), nil)
+	doJSON(t, r, "POST", rotateURL.String(), nil)
+
+	publicURL, _ := r.Get(routes.RouteNameExportKey).URL("type", "public-key", "name", testKey1)
+	code, exported := doJSON(t, r, "GET", publicURL.String(), nil)
+	require.Equal(t, http.StatusOK, code, exported)
+	assert.Equal(t, "public-key", exported["export_type"])
+	assert.Equal(t, "ml-kem-768", exported["type"])
+	assert.Equal(t, "pem", exported["format"])
+	_, meta := doJSON(t, r, "GET", exportableURL.String(), nil)
+	versions := meta["versions"].([]interface{})
+	publicKeyPEM := func(i int) string {
+		pub, err := base64.StdEncoding.DecodeString(versions[i].(map[string]interface{})["public_key"].(string))
+		require.NoError(t, err)
+		der, err := kybertransit.MarshalPKIXPublicKey(kybertransit.KeyTypeMLKEM768, pub)
+		require.NoError(t, err)
+		return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	}
+	assert.Equal(t, map[string]interface{}{"1": publicKeyPEM(0), "2": publicKeyPEM(1)}, exported["keys"])
+
+	code, enc := doJSON(t, r, "POST", encURL.String(), map[string]string{"plaintext": b64("exported")})
+	require.Equal(t, http.StatusOK, code, enc)
+	privateURL, _ := r.Get(routes.RouteNameExportVersion).URL("type", "private-key", "name", testKey1, "version", "2")
+	code, exported = doJSON(t, r, "GET", privateURL.String(), nil)
+	require.Equal(t, http.StatusOK, code, exported)
+	keys := exported["keys"].(map[string]interface{})
+	require.Len(t, keys, 1)
+	block, _ := pem.Decode([]byte(keys["2"].(string)))
+	require.NotNil(t, block)
+	assert.Equal(t, "PRIVATE KEY", block.Type)
+	keyType, privateKey, err := kybertransit.ParsePKCS8PrivateKey(block.Bytes)
+	require.NoError(t, err)
+	assert.Equal(t, kybertransit.KeyTypeMLKEM768, keyType)
+	env, err := kybertransit.ParseEnvelope(enc["ciphertext"].(string))
+	require.NoError(t, err)
+	pt, err := kybertransit.DecryptEnvelope(kybertransit.KeyTypeMLKEM768, privateKey, env, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("exported"), pt, "exported private key decrypts")
+
+	// kyber1024 has no standard encoding and is exported as raw key bytes.
+	rawURL, _ := r.Get(routes.RouteNameExportVersion).URL("type", "public-key", "name", testKey2, "version", "1")
+	code, exported = doJSON(t, r, "GET", rawURL.String(), nil)
+	require.Equal(t, http.StatusOK, code, exported)
+	assert.Equal(t, "raw", exported["format"])
+	_, meta = doJSON(t, r, "GET", plainURL.String(), nil)
+	assert.Equal(t, map[string]interface{}{
+		"1": meta["versions"].([]interface{})[0].(map[string]interface{})["public_key"],
+	}, exported["keys"])
+
+	tests := []struct {
+		name       string
+		url        string
+		wantStatus int
+		wantError  string
+	}{
+		{"all private keys", "/transit/export/private-key/" + testKey1, http.StatusOK, ""},
+		{"public key of non-exportable key", "/transit/export/public-key/" + testKey2 + "/1", http.StatusOK, ""},
+		{"private key of non-exportable key", "/transit/export/private-key/" + testKey2, http.StatusBadRequest, "Key is not exportable: set exportable to export private keys"},
+		{"unknown key", "/transit/export/public-key/" + unknownKey, http.StatusNotFound, "Key not found"},
+		{"unknown version", "/transit/export/public-key/" + testKey1 + "/3", http.StatusNotFound, "Key version not found"},
+		{"invalid version", "/transit/export/public-key/" + testKey1 + "/latest", http.StatusBadRequest, "Invalid key version"},
+		{"unknown export type", "/transit/export/secret-key/" + testKey1, http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, resp := doJSON(t, r, "GET", tt.url, nil)
+			assert.Equal(t, tt.wantStatus, code)
+			if tt.wantError != "" {
+				assert.Equal(t, tt.wantError, resp["error"])
+			}
+		})
+	}
+}
@@ -3,60 +3,78 @@ package handlers
 import (
 	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"log"
 	"net/http"
-	"sync"
+	"slices"
+	"unicode/utf8"
 
+	"github.com/dezween/ElevexaCodingChallenge2/internal/auth"
+	"github.com/dezween/ElevexaCodingChallenge2/internal/barrier"
 	"github.com/dezween/ElevexaCodingChallenge2/internal/kybertransit"
+	"github.com/dezween/ElevexaCodingChallenge2/internal/policy"
+	"github.com/dezween/ElevexaCodingChallenge2/internal/storage"
 	"github.com/gorilla/mux"
 )
 
-// KeyStoreManager manages Kyber key pairs in a thread-safe in-memory store.
-type KeyStoreManager struct {
-	store map[string]kybertransit.KeyPair
-	mu    sync.RWMutex
-}
+var (
+	// keyBarrier encrypts everything the handlers persist with the master key.
+	// It starts sealed; transit endpoints return 503 until POST /sys/unseal succeeds.
+	keyBarrier = barrier.New(storage.NewMemory())
+	// keyStoreManager holds the named keys served by the handlers, behind keyBarrier.
+	// It defaults to volatile in-memory storage; call UseStorage to persist keys.
+	keyStoreManager = NewKeyStoreManager(keyBarrier)
+	// tokenStore holds hashed API tokens in the same storage as the keys.
+	tokenStore = auth.NewTokenStore(keyBarrier)
+	// policyStore holds the ACL policies attached to tokens.
+	policyStore = policy.NewStore(keyBarrier)
+)
 
-// NewKeyStoreManager creates a new in-memory key store manager.
-func NewKeyStoreManager() *KeyStoreManager {
-	return &KeyStoreManager{store: make(map[string]kybertransit.KeyPair)}
+// UseStorage makes the handlers keep keys, tokens and policies in the given storage backend,
+// encrypted by a new sealed barrier. Must be called before the router starts serving requests.
+func UseStorage(s storage.Storage) {
+	keyBarrier = barrier.New(s)
+	keyStoreManager = NewKeyStoreManager(keyBarrier)
+	tokenStore = auth.NewTokenStore(keyBarrier)
+	policyStore = policy.NewStore(keyBarrier)
 }
 
-// CreateKey creates a new Kyber key pair with the given name.
-func (m *KeyStoreManager) CreateKey(name string) (kybertransit.KeyPair, bool, error) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	if _, exists := m.store[name]; exists {
-		return kybertransit.KeyPair{}, true, nil
-	}
-	kp, err := kybertransit.GenerateKeyPair()
-	if err != nil {
-		return kybertransit.KeyPair{}, false, err
-	}
-	m.store[name] = kp
-	return kp, false, nil
+// apiError is an error carrying an HTTP status and a message that is safe to return to clients.
+type apiError struct {
+	status  int
+	message string
 }
 
-// GetKey returns the Kyber key pair by name.
-func (m *KeyStoreManager) GetKey(name string) (kybertransit.KeyPair, bool) {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
-	kp, exists := m.store[name]
-	return kp, exists
+func (e *apiError) Error() string { return e.message }
+
+// badRequest returns an apiError with status 400.
+func badRequest(message string) *apiError {
+	return &apiError{status: http.StatusBadRequest, message: message}
 }
 
-// Reset clears all keys (for test isolation).
-func (m *KeyStoreManager) Reset() {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	m.store = make(map[string]kybertransit.KeyPair)
+// unsupportedOperation returns the 400 error for an operation the key's type cannot perform.
+func unsupportedOperation(key Key, operation string) *apiError {
+	return badRequest(fmt.Sprintf("Key type %s does not support %s", key.Type, operation))
 }
 
-// keyStore is a volatile in-memory key-value store for Kyber key pairs.
-// All keys are lost on server restart. Not for production use.
-// TODO: Use persistent storage for production.
-var keyStoreManager = NewKeyStoreManager()
+// writeError writes err as a JSON error response.
+// A sealed barrier is reported as 503; errors other than *apiError are logged and
+// reported as a generic 500.
+func writeError(w http.ResponseWriter, err error) {
+	var apiErr *apiError
+	if errors.As(err, &apiErr) {
+		writeJSON(w, apiErr.status, map[string]string{"error": apiErr.message})
+		return
+	}
+	if errors.Is(err, barrier.ErrSealed) {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "Server is sealed"})
+		return
+	}
+	log.Printf("[ERROR] %v", err)
+	writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Internal error"})
+}
 
 // writeJSON writes a JSON response with the given status code.
 // Logs encoding errors.
@@ -69,105 +87,488 @@ func writeJSON(w http.ResponseWriter, status int, data interface{}) {
 }
 
 // CreateKeyHandler handles POST /transit/keys/{name}.
-// Generates a new Kyber key pair and stores it in memory.
-// Returns 201 on success, 409 if key exists, 500 on internal error.
+// Generates a new key pair of the requested "type" (default kyber1024; ml-dsa-* types
+// create signing keys) as version 1 of the key and stores it together with the
+// optional "deletion_allowed", "exportable" and "allow_plaintext_backup" flags and
+// "auto_rotate_period" duration (e.g. "720h"; 0 or omitted disables auto rotation).
+// With "derived" every encrypt and decrypt call must supply a "context", from which
+// the DEM key is derived; it cannot be changed later. "convergent_encryption" (derived
+// keys only, also fixed at creation) makes encryption deterministic: the same plaintext,
+// context and associated_data always produce the same ciphertext, which reveals equal
+// plaintexts but allows exact-match lookups on encrypted fields.
+// Returns 201 on success, 400 on unsupported type or invalid config, 409 if key exists,
+// 500 on internal error.
 func CreateKeyHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	name := vars["name"]
-	kp, exists, err := keyStoreManager.CreateKey(name)
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+		return
+	}
+	var req struct {
+		Type                 string `json:"type"`
+		DeletionAllowed      bool   `json:"deletion_allowed"`
+		Exportable           bool   `json:"exportable"`
+		AllowPlaintextBackup bool   `json:"allow_plaintext_backup"`
+		AutoRotatePeriod     string `json:"auto_rotate_period"`
+		Derived              bool   `json:"derived"`
+		ConvergentEncryption bool   `json:"convergent_encryption"`
+	}
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &req); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid JSON"})
+			return
+		}
+	}
+	keyType, err := kybertransit.ParseKeyType(req.Type)
 	if err != nil {
-		log.Printf("[ERROR] failed to generate key pair: %v", err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Internal error"})
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("Unsupported key type %q", req.Type)})
+		return
+	}
+	autoRotatePeriod, ok := parseAutoRotatePeriod(w, req.AutoRotatePeriod)
+	if !ok {
+		return
+	}
+	key, exists, err := keyStoreManager.CreateKey(name, keyType, KeyConfig{
+		DeletionAllowed:      req.DeletionAllowed,
+		Exportable:           req.Exportable,
+		AllowPlaintextBackup: req.AllowPlaintextBackup,
+		AutoRotatePeriod:     autoRotatePeriod,
+		Derived:              req.Derived,
+		ConvergentEncryption: req.ConvergentEncryption,
+	})
+	if errors.Is(err, ErrInvalidKeyConfig) {
+		invalidKeyConfig(w, err)
+		return
+	}
+	if err != nil {
+		writeError(w, fmt.Errorf("failed to create key: %w", err))
 		return
 	}
 	if exists {
 		writeJSON(w, http.StatusConflict, map[string]string{"error": "Key already exists"})
 		return
 	}
-	writeJSON(w, http.StatusCreated, map[string]string{
-		"message":    "Key created",
-		"public_key": base64.StdEncoding.EncodeToString(kp.PublicKey),
+	setAuditKeyVersion(r, key.LatestVersion())
+	writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"message":        "Key created",
+		"type":           key.Type,
+		"latest_version": key.LatestVersion(),
+		"public_key":     base64.StdEncoding.EncodeToString(key.Latest().KeyPair.PublicKey),
+	})
+}
+
+// RotateKeyHandler handles POST /transit/keys/{name}/rotate.
+// Adds a new key version; subsequent encryptions use it, older versions remain for decryption.
+// Returns 200 on success, 404 if key not found, 500 on internal error.
+func RotateKeyHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	name := vars["name"]
+	key, err := keyStoreManager.RotateKey(name)
+	if errors.Is(err, ErrKeyNotFound) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "Key not found"})
+		return
+	}
+	if err != nil {
+		writeError(w, fmt.Errorf("failed to rotate key: %w", err))
+		return
+	}
+	setAuditKeyVersion(r, key.LatestVersion())
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"message":        "Key rotated",
+		"type":           key.Type,
+		"latest_version": key.LatestVersion(),
+		"public_key":     base64.StdEncoding.EncodeToString(key.Latest().KeyPair.PublicKey),
 	})
 }
 
 // EncryptHandler handles POST /transit/encrypt/{name}.
-// Encrypts plaintext using the Kyber public key.
-// Returns 200 and ciphertext+encdata on success, 404 if key not found, 400/500 on error.
+// Encrypts the base64 "plaintext" (or plain text with "encoding": "text"), binding it to
+// the optional base64 "associated_data" (and, for derived keys, the required base64
+// "context"), using the public key of the latest key version (or the requested
+// "key_version", subject to min_encryption_version) and returns a self-describing
+// "<type>:v<version>:<base64>" ciphertext token.
+// With "batch_input" every item is encrypted and reported in "batch_results" (see batch.go).
+// Returns 200 and ciphertext+key_version on success, 404 if key not found, 400/500 on error.
 func EncryptHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	name := vars["name"]
-	key, exists := keyStoreManager.GetKey(name)
-	if !exists {
+	key, err := keyStoreManager.GetKey(name)
+	if errors.Is(err, ErrKeyNotFound) {
 		writeJSON(w, http.StatusNotFound, map[string]string{"error": "Key not found"})
 		return
 	}
-	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+		writeError(w, err)
+		return
+	}
+	if !key.Type.SupportsEncryption() {
+		writeError(w, unsupportedOperation(key, "encryption"))
+		return
+	}
+	body, err := readBatchBody(w, r)
+	if err != nil {
+		writeError(w, err)
 		return
 	}
 	var req struct {
-		Plaintext string `json:"plaintext"`
+		encryptItem
+		BatchInput []encryptItem `json:"batch_input"`
 	}
 	if err := json.Unmarshal(body, &req); err != nil {
 		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid JSON"})
 		return
 	}
-	if req.Plaintext == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Missing plaintext"})
+	if req.BatchInput != nil {
+		if err := checkBatchInput(len(req.BatchInput), req.Plaintext != ""); err != nil {
+			writeError(w, err)
+			return
+		}
+		results := make([]map[string]interface{}, len(req.BatchInput))
+		versions := make([]int, len(req.BatchInput))
+		runBatch(len(req.BatchInput), func(i int) {
+			resp, version, err := encryptPlaintext(key, req.BatchInput[i])
+			versions[i] = version
+			results[i] = batchResult(resp, err)
+		})
+		// A batch may span key versions; the newest one used is audited.
+		setAuditKeyVersion(r, slices.Max(versions))
+		writeJSON(w, http.StatusOK, map[string]interface{}{"batch_results": results})
 		return
 	}
-	ct, encdata, err := kybertransit.Encrypt(key.PublicKey, []byte(req.Plaintext))
+	resp, version, err := encryptPlaintext(key, req.encryptItem)
+	setAuditKeyVersion(r, version)
 	if err != nil {
-		log.Printf("[ERROR] encrypt failed: %v", err)
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Encryption failed: invalid input or internal error"})
+		writeError(w, err)
 		return
 	}
-	writeJSON(w, http.StatusOK, map[string]string{
-		"ciphertext": ct,
-		"encdata":    encdata,
-	})
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// Plaintext encodings selected by the "encoding" field of encrypt and decrypt requests.
+// Plaintexts are base64 by default so binary data survives JSON; "text" passes UTF-8
+// strings through unchanged.
+const (
+	encodingBase64 = "base64"
+	encodingText   = "text"
+)
+
+// checkEncoding rejects encodings other than base64 (the default when empty) and text.
+func checkEncoding(encoding string) error {
+	switch encoding {
+	case "", encodingBase64, encodingText:
+		return nil
+	}
+	return badRequest(fmt.Sprintf("Unsupported encoding %q: must be %q or %q", encoding, encodingBase64, encodingText))
+}
+
+// decodePlaintext returns the raw bytes of a plaintext in the given encoding.
+func decodePlaintext(plaintext, encoding string) ([]byte, error) {
+	if err := checkEncoding(encoding); err != nil {
+		return nil, err
+	}
+	if encoding == encodingText {
+		return []byte(plaintext), nil
+	}
+	data, err := base64.StdEncoding.DecodeString(plaintext)
+	if err != nil {
+		return nil, badRequest(`Invalid plaintext: must be base64 (or set "encoding": "text")`)
+	}
+	return data, nil
+}
+
+// encodePlaintext renders decrypted bytes in the given encoding. The text encoding
+// fails for plaintexts that are not valid UTF-8.
+func encodePlaintext(plaintext []byte, encoding string) (string, error) {
+	if err := checkEncoding(encoding); err != nil {
+		return "", err
+	}
+	if encoding != encodingText {
+		return base64.StdEncoding.EncodeToString(plaintext), nil
+	}
+	if !utf8.Valid(plaintext) {
+		return "", badRequest("Plaintext is not valid UTF-8: use base64 encoding")
+	}
+	return string(plaintext), nil
+}
+
+// decodeAssociatedData decodes the optional base64 "associated_data" of an item.
+// Associated data is authenticated with the ciphertext but not encrypted or stored, so
+// the same value must be supplied to decrypt.
+func decodeAssociatedData(associatedData string) ([]byte, error) {
+	if associatedData == "" {
+		return nil, nil
+	}
+	data, err := base64.StdEncoding.DecodeString(associatedData)
+	if err != nil {
+		return nil, badRequest("Invalid associated_data: must be base64")
+	}
+	return data, nil
+}
+
+// keyContext decodes the base64 "context" of an item. Derived keys require a context,
+// which selects the DEM key; other keys reject one.
+func keyContext(key Key, context string) ([]byte, error) {
+	if !key.Derived {
+		if context != "" {
+			return nil, badRequest("context is only supported for derived keys")
+		}
+		return nil, nil
+	}
+	if context == "" {
+		return nil, badRequest("Missing context: key is derived")
+	}
+	data, err := base64.StdEncoding.DecodeString(context)
+	if err != nil {
+		return nil, badRequest("Invalid context: must be base64")
+	}
+	return data, nil
+}
+
+// encryptItem is the input of a single encryption.
+// KeyVersion selects an older key version; 0 means the latest.
+type encryptItem struct {
+	Plaintext      string `json:"plaintext"`
+	Encoding       string `json:"encoding"`
+	AssociatedData string `json:"associated_data"`
+	Context        string `json:"context"`
+	KeyVersion     int    `json:"key_version"`
+}
+
+// encryptPlaintext encrypts one item with the requested (default: latest) version of key.
+// The version is returned whenever the requested version is valid (0 otherwise), so it
+// can be audited even if the item fails for another reason.
+func encryptPlaintext(key Key, item encryptItem) (map[string]interface{}, int, error) {
+	kv, versionErr := encryptionVersion(key, item.KeyVersion)
+	if item.Plaintext == "" {
+		return nil, kv.Version, badRequest("Missing plaintext")
+	}
+	plaintext, err := decodePlaintext(item.Plaintext, item.Encoding)
+	if err != nil {
+		return nil, kv.Version, err
+	}
+	associatedData, err := decodeAssociatedData(item.AssociatedData)
+	if err != nil {
+		return nil, kv.Version, err
+	}
+	context, err := keyContext(key, item.Context)
+	if err != nil {
+		return nil, kv.Version, err
+	}
+	if versionErr != nil {
+		return nil, 0, versionErr
+	}
+	resp, err := sealPlaintext(key, kv, plaintext, associatedData, context)
+	return resp, kv.Version, err
+}
+
+// encryptionVersion returns the key version to encrypt with: the latest for 0,
+// otherwise the requested version if the key's min versions allow it.
+func encryptionVersion(key Key, version int) (KeyVersion, error) {
+	if version == 0 {
+		return key.Latest(), nil
+	}
+	if version < key.MinEncryptionVersion {
+		return KeyVersion{}, badRequest(fmt.Sprintf("Key version %d is below min_encryption_version %d", version, key.MinEncryptionVersion))
+	}
+	if version < key.MinDecryptionVersion {
+		return KeyVersion{}, badRequest(fmt.Sprintf("Key version %d is below min_decryption_version %d", version, key.MinDecryptionVersion))
+	}
+	kv, ok := key.Version(version)
+	if !ok {
+		return KeyVersion{}, badRequest("Key version not found")
+	}
+	return kv, nil
+}
+
+// sealPlaintext encrypts plaintext bound to associatedData and context (either may be nil)
+// with the given version of key and renders the ciphertext token and key version.
+// Keys with convergent_encryption encrypt deterministically.
+func sealPlaintext(key Key, kv KeyVersion, plaintext, associatedData, context []byte) (map[string]interface{}, error) {
+	var ct string
+	var err error
+	if key.ConvergentEncryption {
+		ct, err = kybertransit.EncryptEnvelopeConvergent(key.Type, kv.KeyPair, kv.Version, plaintext, associatedData, context)
+	} else {
+		ct, err = kybertransit.EncryptEnvelope(key.Type, kv.KeyPair.PublicKey, kv.Version, plaintext, associatedData, context)
+	}
+	if err != nil {
+		log.Printf("[ERROR] encrypt failed: %v", err)
+		return nil, badRequest("Encryption failed: invalid input or internal error")
+	}
+	return map[string]interface{}{
+		"ciphertext":  ct,
+		"key_version": kv.Version,
+	}, nil
 }
 
 // DecryptHandler handles POST /transit/decrypt/{name}.
-// Decrypts ciphertext+encdata using the Kyber private key.
+// Accepts either a "<type>:v<version>:<base64>" ciphertext token or the legacy
+// ciphertext+encdata pair, and decrypts with the key version recorded in the ciphertext.
+// The plaintext is returned base64-encoded, or as text with "encoding": "text".
+// Ciphertexts bound to "associated_data" only decrypt with the same associated_data,
+// and ciphertexts of derived keys only with the same "context".
+// With "batch_input" every item is decrypted and reported in "batch_results" (see batch.go).
 // Returns 200 and plaintext on success, 404 if key not found, 400/500 on error.
 func DecryptHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	name := vars["name"]
-	key, exists := keyStoreManager.GetKey(name)
-	if !exists {
+	key, err := keyStoreManager.GetKey(name)
+	if errors.Is(err, ErrKeyNotFound) {
 		writeJSON(w, http.StatusNotFound, map[string]string{"error": "Key not found"})
 		return
 	}
-	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+		writeError(w, err)
+		return
+	}
+	if !key.Type.SupportsEncryption() {
+		writeError(w, unsupportedOperation(key, "decryption"))
+		return
+	}
+	body, err := readBatchBody(w, r)
+	if err != nil {
+		writeError(w, err)
 		return
 	}
 	var req struct {
-		Ciphertext string `json:"ciphertext"`
-		Encdata    string `json:"encdata"`
+		decryptItem
+		BatchInput []decryptItem `json:"batch_input"`
 	}
 	if err := json.Unmarshal(body, &req); err != nil {
 		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid JSON"})
 		return
 	}
-	if req.Ciphertext == "" || req.Encdata == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Missing ciphertext or encdata"})
+	if req.BatchInput != nil {
+		if err := checkBatchInput(len(req.BatchInput), req.Ciphertext != ""); err != nil {
+			writeError(w, err)
+			return
+		}
+		results := make([]map[string]interface{}, len(req.BatchInput))
+		versions := make([]int, len(req.BatchInput))
+		runBatch(len(req.BatchInput), func(i int) {
+			resp, version, err := decryptToPlaintext(key, req.BatchInput[i])
+			versions[i] = version
+			results[i] = batchResult(resp, err)
+		})
+		// A batch may span key versions; the newest one used is audited.
+		setAuditKeyVersion(r, slices.Max(versions))
+		writeJSON(w, http.StatusOK, map[string]interface{}{"batch_results": results})
 		return
 	}
-	plaintext, err := kybertransit.Decrypt(key.PrivateKey, req.Ciphertext, req.Encdata)
+	plaintext, version, err := decryptItemWithKey(key, req.decryptItem)
+	setAuditKeyVersion(r, version)
 	if err != nil {
-		log.Printf("[ERROR] decrypt failed: %v", err)
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Decryption failed: invalid ciphertext, encdata, or internal error"})
+		writeError(w, err)
+		return
+	}
+	encoded, err := encodePlaintext(plaintext, req.Encoding)
+	if err != nil {
+		writeError(w, err)
 		return
 	}
 	writeJSON(w, http.StatusOK, map[string]string{
-		"plaintext": plaintext,
+		"plaintext": encoded,
 	})
 }
 
+// decryptItem is the input of a single decryption.
+// Encoding selects how the plaintext is returned; rewrap ignores it.
+type decryptItem struct {
+	Ciphertext     string `json:"ciphertext"`
+	Encdata        string `json:"encdata"`
+	Encoding       string `json:"encoding"`
+	AssociatedData string `json:"associated_data"`
+	Context        string `json:"context"`
+}
+
+// decryptItemWithKey validates and decrypts one item.
+func decryptItemWithKey(key Key, item decryptItem) ([]byte, int, error) {
+	if item.Ciphertext == "" {
+		return nil, 0, badRequest("Missing ciphertext")
+	}
+	associatedData, err := decodeAssociatedData(item.AssociatedData)
+	if err != nil {
+		return nil, 0, err
+	}
+	context, err := keyContext(key, item.Context)
+	if err != nil {
+		return nil, 0, err
+	}
+	return decryptCiphertext(key, item.Ciphertext, item.Encdata, associatedData, context)
+}
+
+// decryptToPlaintext decrypts one item and renders the plaintext in the item's encoding.
+// The key version recorded in the ciphertext is returned as by decryptItemWithKey.
+func decryptToPlaintext(key Key, item decryptItem) (map[string]interface{}, int, error) {
+	plaintext, version, err := decryptItemWithKey(key, item)
+	if err != nil {
+		return nil, version, err
+	}
+	encoded, err := encodePlaintext(plaintext, item.Encoding)
+	if err != nil {
+		return nil, version, err
+	}
+	return map[string]interface{}{"plaintext": encoded}, version, nil
+}
+
+// decryptCiphertext decrypts a ciphertext token bound to associatedData, or a legacy
+// ciphertext+encdata pair when encdata is set, using the key version recorded in the
+// ciphertext and the derivation context of derived keys. The version is returned (0 if
+// the ciphertext could not be parsed) so it can be audited.
+func decryptCiphertext(key Key, ciphertext, encdata string, associatedData, context []byte) ([]byte, int, error) {
+	var (
+		version int
+		decrypt func(privKey []byte) ([]byte, error)
+	)
+	if encdata != "" {
+		if associatedData != nil {
+			return nil, 0, badRequest("associated_data is not supported with the legacy ciphertext+encdata form")
+		}
+		v, b64ct, err := kybertransit.SplitVersionPrefix(ciphertext)
+		if err != nil {
+			return nil, 0, badRequest("Invalid ciphertext format")
+		}
+		version = v
+		decrypt = func(privKey []byte) ([]byte, error) {
+			return kybertransit.Decrypt(key.Type, privKey, b64ct, encdata, context)
+		}
+	} else {
+		env, err := kybertransit.ParseEnvelope(ciphertext)
+		if err != nil {
+			log.Printf("[ERROR] invalid ciphertext envelope: %v", err)
+			return nil, 0, badRequest("Invalid ciphertext format")
+		}
+		if env.Algorithm != key.Type.Algorithm() {
+			return nil, 0, badRequest("Ciphertext algorithm does not match key type")
+		}
+		version = env.KeyVersion
+		decrypt = func(privKey []byte) ([]byte, error) {
+			return kybertransit.DecryptEnvelope(key.Type, privKey, env, associatedData, context)
+		}
+	}
+	if version < key.MinDecryptionVersion {
+		return nil, version, badRequest(fmt.Sprintf("Key version %d is below min_decryption_version %d", version, key.MinDecryptionVersion))
+	}
+	kv, ok := key.Version(version)
+	if !ok {
+		return nil, version, badRequest("Key version not found")
+	}
+	plaintext, err := decrypt(kv.KeyPair.PrivateKey)
+	if encdata == "" && errors.Is(err, kybertransit.ErrAuthentication) {
+		if key.Derived {
+			return nil, version, badRequest("Decryption failed: ciphertext was modified or context or associated_data does not match")
+		}
+		return nil, version, badRequest("Decryption failed: ciphertext was modified or associated_data does not match")
+	}
+	if err != nil {
+		log.Printf("[ERROR] decrypt failed: %v", err)
+		return nil, version, badRequest("Decryption failed: invalid ciphertext, encdata, or internal error")
+	}
+	return plaintext, version, nil
+}
+
 // HealthHandler returns 200 OK for health checks.
 func HealthHandler(w http.ResponseWriter, _ *http.Request) {
 	w.WriteHeader(http.StatusOK)
@@ -176,7 +577,8 @@ func HealthHandler(w http.ResponseWriter, _ *http.Request) {
 	}
 }
 
-// ResetKeyStore clears the in-memory store. Intended for tests to ensure isolation.
+// ResetKeyStore discards all keys and starts over with a fresh in-memory storage
+// behind a new, uninitialized and sealed barrier. Intended for tests to ensure isolation.
 func ResetKeyStore() {
-	keyStoreManager.Reset()
+	UseStorage(storage.NewMemory())
 }
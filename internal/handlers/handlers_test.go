@@ -6,12 +6,16 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/dezween/ElevexaCodingChallenge2/internal/handlers"
+	"github.com/dezween/ElevexaCodingChallenge2/internal/kybertransit"
 	"github.com/dezween/ElevexaCodingChallenge2/internal/routes"
 	"github.com/dezween/ElevexaCodingChallenge2/internal/server"
+	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 const (
@@ -23,8 +27,7 @@ const (
 
 func TestCreateKeyHandler_TableDriven(t *testing.T) {
 	// Ensure test isolation
-	handlers.ResetKeyStore()
-	r := server.NewRouter()
+	r := newTestRouter(t)
 	tests := []struct {
 		name       string
 		keyName    string
@@ -65,8 +68,7 @@ func TestCreateKeyHandler_TableDriven(t *testing.T) {
 }
 
 func TestEncryptHandler_TableDriven(t *testing.T) {
-	handlers.ResetKeyStore()
-	r := server.NewRouter()
+	r := newTestRouter(t)
 	// First, we create a key
 	createURL, _ := r.Get(routes.RouteNameCreateKey).URL("name", testKey2)
 	req := httptest.NewRequest("POST", createURL.String(), nil)
@@ -81,8 +83,8 @@ func TestEncryptHandler_TableDriven(t *testing.T) {
 		wantField  string
 		wantValue  string
 	}{
-		{"success", testKey2, map[string]string{"plaintext": "hello quantum world"}, http.StatusOK, "ciphertext", ""},
-		{"unknown key", unknownKey, map[string]string{"plaintext": "data"}, http.StatusNotFound, "error", "Key not found"},
+		{"success", testKey2, map[string]string{"plaintext": b64("hello quantum world")}, http.StatusOK, "ciphertext", ""},
+		{"unknown key", unknownKey, map[string]string{"plaintext": b64("data")}, http.StatusNotFound, "error", "Key not found"},
 		{"invalid JSON", testKey2, "notjson", http.StatusBadRequest, "error", "Invalid JSON"},
 		{"missing plaintext", testKey2, map[string]string{}, http.StatusBadRequest, "error", "Missing plaintext"},
 	}
@@ -112,24 +114,29 @@ func TestEncryptHandler_TableDriven(t *testing.T) {
 }
 
 func TestDecryptHandler_TableDriven(t *testing.T) {
-	handlers.ResetKeyStore()
-	r := server.NewRouter()
+	r := newTestRouter(t)
 	// First, we create a key and encrypt the data.
 	createURL, _ := r.Get(routes.RouteNameCreateKey).URL("name", testKey3)
 	req := httptest.NewRequest("POST", createURL.String(), nil)
 	w := httptest.NewRecorder()
 	r.ServeHTTP(w, req)
+	var createResp map[string]interface{}
+	_ = json.Unmarshal(w.Body.Bytes(), &createResp)
+	pubKey, _ := base64.StdEncoding.DecodeString(createResp["public_key"].(string))
 
-	encReq := map[string]string{"plaintext": "data"}
+	encReq := map[string]string{"plaintext": b64("data")}
 	encBody, _ := json.Marshal(encReq)
 	encURL, _ := r.Get(routes.RouteNameEncrypt).URL("name", testKey3)
 	req = httptest.NewRequest("POST", encURL.String(), bytes.NewReader(encBody))
 	w = httptest.NewRecorder()
 	r.ServeHTTP(w, req)
-	var encResp map[string]string
+	var encResp map[string]interface{}
 	_ = json.Unmarshal(w.Body.Bytes(), &encResp)
-	ct := encResp["ciphertext"]
-	encdata := encResp["encdata"]
+	ct := encResp["ciphertext"].(string)
+
+	// Legacy two-field form, as produced before the envelope format existed.
+	legacyCT, legacyEnc, err := kybertransit.Encrypt(kybertransit.DefaultKeyType, pubKey, []byte("legacy data"), nil)
+	assert.NoError(t, err)
 
 	tests := []struct {
 		name       string
@@ -139,11 +146,15 @@ func TestDecryptHandler_TableDriven(t *testing.T) {
 		wantField  string
 		wantValue  string
 	}{
-		{"success", testKey3, map[string]string{"ciphertext": ct, "encdata": encdata}, http.StatusOK, "plaintext", "data"},
-		{"unknown key", unknownKey, map[string]string{"ciphertext": ct, "encdata": encdata}, http.StatusNotFound, "error", "Key not found"},
-		{"invalid ciphertext", testKey3, map[string]string{"ciphertext": base64.StdEncoding.EncodeToString([]byte("bad")), "encdata": encdata}, http.StatusBadRequest, "error", "Decryption failed: invalid ciphertext, encdata, or internal error"},
-		{"missing ciphertext", testKey3, map[string]string{"encdata": encdata}, http.StatusBadRequest, "error", "Missing ciphertext or encdata"},
-		{"missing encdata", testKey3, map[string]string{"ciphertext": ct}, http.StatusBadRequest, "error", "Missing ciphertext or encdata"},
+		{"success", testKey3, map[string]string{"ciphertext": ct}, http.StatusOK, "plaintext", b64("data")},
+		{"legacy two-field form", testKey3, map[string]string{"ciphertext": legacyCT, "encdata": legacyEnc}, http.StatusOK, "plaintext", b64("legacy data")},
+		{"legacy prefixed two-field form", testKey3, map[string]string{"ciphertext": "kyber:v1:" + legacyCT, "encdata": legacyEnc}, http.StatusOK, "plaintext", b64("legacy data")},
+		{"unknown key", unknownKey, map[string]string{"ciphertext": ct}, http.StatusNotFound, "error", "Key not found"},
+		{"invalid ciphertext", testKey3, map[string]string{"ciphertext": "kyber:v1:" + base64.StdEncoding.EncodeToString([]byte("bad"))}, http.StatusBadRequest, "error", "Invalid ciphertext format"},
+		{"not an envelope", testKey3, map[string]string{"ciphertext": legacyCT}, http.StatusBadRequest, "error", "Invalid ciphertext format"},
+		{"tampered ciphertext", testKey3, map[string]string{"ciphertext": tamperToken(ct)}, http.StatusBadRequest, "error", "Decryption failed: ciphertext was modified or associated_data does not match"},
+		{"tampered encdata", testKey3, map[string]string{"ciphertext": legacyCT, "encdata": tamper(legacyEnc)}, http.StatusBadRequest, "error", "Decryption failed: invalid ciphertext, encdata, or internal error"},
+		{"missing ciphertext", testKey3, map[string]string{"encdata": legacyEnc}, http.StatusBadRequest, "error", "Missing ciphertext"},
 	}
 
 	for _, tt := range tests {
@@ -160,3 +171,447 @@ func TestDecryptHandler_TableDriven(t *testing.T) {
 		})
 	}
 }
+
+// tamper flips one bit in the last byte of a base64-encoded value.
+func tamper(b64 string) string {
+	raw, _ := base64.StdEncoding.DecodeString(b64)
+	if len(raw) > 0 {
+		raw[len(raw)-1] ^= 0x01
+	}
+	return base64.StdEncoding.EncodeToString(raw)
+}
+
+// tamperToken flips one bit in the sealed data of a "kyber:v<version>:<base64>" token.
+func tamperToken(token string) string {
+	i := strings.LastIndex(token, ":")
+	return token[:i+1] + tamper(token[i+1:])
+}
+
+// testRouter is a router that authenticates requests without an Authorization
+// header using its root token.
+type testRouter struct {
+	*mux.Router
+	rootToken string
+}
+
+func (tr *testRouter) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Header.Get("Authorization") == "" {
+		req.Header.Set("Authorization", "Bearer "+tr.rootToken)
+	}
+	tr.Router.ServeHTTP(w, req)
+}
+
+// newTestRouter resets the key store, initializes it with a single unseal key
+// and returns an unsealed router that sends the root token by default.
+func newTestRouter(t *testing.T) *testRouter {
+	t.Helper()
+	handlers.ResetKeyStore()
+	r := server.NewRouter()
+	initURL, _ := r.Get(routes.RouteNameInit).URL()
+	unsealURL, _ := r.Get(routes.RouteNameUnseal).URL()
+	code, resp := doJSON(t, r, "POST", initURL.String(), map[string]int{"secret_shares": 1, "secret_threshold": 1})
+	require.Equal(t, http.StatusOK, code, resp)
+	key := resp["keys"].([]interface{})[0]
+	rootToken := resp["root_token"].(string)
+	code, resp = doJSON(t, r, "POST", unsealURL.String(), map[string]interface{}{"key": key})
+	require.Equal(t, http.StatusOK, code, resp)
+	return &testRouter{Router: r, rootToken: rootToken}
+}
+
+// b64 returns the standard base64 encoding of s, as sent and returned in "plaintext".
+func b64(s string) string {
+	return base64.StdEncoding.EncodeToString([]byte(s))
+}
+
+// doJSON sends a JSON request through the router and decodes the JSON response.
+func doJSON(t *testing.T, r http.Handler, method, url string, body interface{}) (int, map[string]interface{}) {
+	t.Helper()
+	var bodyBytes []byte
+	if body != nil {
+		bodyBytes, _ = json.Marshal(body)
+	}
+	req := httptest.NewRequest(method, url, bytes.NewReader(bodyBytes))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var resp map[string]interface{}
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	return w.Code, resp
+}
+
+func TestRotateKeyHandler(t *testing.T) {
+	r := newTestRouter(t)
+	createURL, _ := r.Get(routes.RouteNameCreateKey).URL("name", testKey1)
+	rotateURL, _ := r.Get(routes.RouteNameRotateKey).URL("name", testKey1)
+	encURL, _ := r.Get(routes.RouteNameEncrypt).URL("name", testKey1)
+	decURL, _ := r.Get(routes.RouteNameDecrypt).URL("name", testKey1)
+
+	code, _ := doJSON(t, r, "POST", createURL.String(), nil)
+	assert.Equal(t, http.StatusCreated, code)
+	code, v1 := doJSON(t, r, "POST", encURL.String(), map[string]string{"plaintext": b64("old")})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, float64(1), v1["key_version"])
+	assert.True(t, strings.HasPrefix(v1["ciphertext"].(string), "kyber:v1:"))
+
+	code, resp := doJSON(t, r, "POST", rotateURL.String(), nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, float64(2), resp["latest_version"])
+
+	code, v2 := doJSON(t, r, "POST", encURL.String(), map[string]string{"plaintext": b64("new")})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, float64(2), v2["key_version"])
+	assert.True(t, strings.HasPrefix(v2["ciphertext"].(string), "kyber:v2:"))
+
+	env, err := kybertransit.ParseEnvelope(v2["ciphertext"].(string))
+	assert.NoError(t, err)
+	env.KeyVersion = 9
+
+	tests := []struct {
+		name       string
+		ciphertext string
+		wantStatus int
+		wantField  string
+		wantValue  string
+	}{
+		{"version 1", v1["ciphertext"].(string), http.StatusOK, "plaintext", b64("old")},
+		{"version 2", v2["ciphertext"].(string), http.StatusOK, "plaintext", b64("new")},
+		{"prefix mismatch", strings.Replace(v2["ciphertext"].(string), "kyber:v2:", "kyber:v1:", 1), http.StatusBadRequest, "error", "Invalid ciphertext format"},
+		{"unknown version", env.String(), http.StatusBadRequest, "error", "Key version not found"},
+		{"malformed version", "kyber:vx:abc", http.StatusBadRequest, "error", "Invalid ciphertext format"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, resp := doJSON(t, r, "POST", decURL.String(), map[string]string{"ciphertext": tt.ciphertext})
+			assert.Equal(t, tt.wantStatus, code)
+			assert.Equal(t, tt.wantValue, resp[tt.wantField])
+		})
+	}
+
+	unknownURL, _ := r.Get(routes.RouteNameRotateKey).URL("name", unknownKey)
+	code, resp = doJSON(t, r, "POST", unknownURL.String(), nil)
+	assert.Equal(t, http.StatusNotFound, code)
+	assert.Equal(t, "Key not found", resp["error"])
+}
+
+func TestCreateKeyHandler_KeyTypes(t *testing.T) {
+	r := newTestRouter(t)
+	small, _ := r.Get(routes.RouteNameCreateKey).URL("name", testKey1)
+	large, _ := r.Get(routes.RouteNameCreateKey).URL("name", testKey2)
+	bad, _ := r.Get(routes.RouteNameCreateKey).URL("name", testKey3)
+
+	code, resp := doJSON(t, r, "POST", small.String(), map[string]string{"type": "kyber512"})
+	require.Equal(t, http.StatusCreated, code, resp)
+	assert.Equal(t, "kyber512", resp["type"])
+	code, resp = doJSON(t, r, "POST", large.String(), nil)
+	require.Equal(t, http.StatusCreated, code, resp)
+	assert.Equal(t, "kyber1024", resp["type"])
+	code, resp = doJSON(t, r, "POST", bad.String(), map[string]string{"type": "kyber2048"})
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, `Unsupported key type "kyber2048"`, resp["error"])
+
+	encURL, _ := r.Get(routes.RouteNameEncrypt).URL("name", testKey1)
+	code, enc := doJSON(t, r, "POST", encURL.String(), map[string]string{"plaintext": b64("iot reading")})
+	require.Equal(t, http.StatusOK, code, enc)
+	env, err := kybertransit.ParseEnvelope(enc["ciphertext"].(string))
+	require.NoError(t, err)
+	assert.Equal(t, kybertransit.AlgorithmKyber512AES256GCM, env.Algorithm)
+
+	decURL, _ := r.Get(routes.RouteNameDecrypt).URL("name", testKey1)
+	code, resp = doJSON(t, r, "POST", decURL.String(), map[string]interface{}{"ciphertext": enc["ciphertext"]})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, b64("iot reading"), resp["plaintext"])
+
+	otherURL, _ := r.Get(routes.RouteNameDecrypt).URL("name", testKey2)
+	code, resp = doJSON(t, r, "POST", otherURL.String(), map[string]interface{}{"ciphertext": enc["ciphertext"]})
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "Ciphertext algorithm does not match key type", resp["error"])
+
+	rotateURL, _ := r.Get(routes.RouteNameRotateKey).URL("name", testKey1)
+	code, resp = doJSON(t, r, "POST", rotateURL.String(), nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "kyber512", resp["type"])
+}
+
+func TestCreateKeyHandler_PostQuantumTypes(t *testing.T) {
+	tests := []struct {
+		keyType   string
+		algorithm kybertransit.Algorithm
+	}{
+		{"ml-kem-768", kybertransit.AlgorithmMLKEM768AES256GCM},
+		{"x25519-ml-kem-768", kybertransit.AlgorithmX25519MLKEM768AES256GCM},
+	}
+	for _, tt := range tests {
+		t.Run(tt.keyType, func(t *testing.T) {
+			testPostQuantumKeyType(t, tt.keyType, tt.algorithm)
+		})
+	}
+}
+
+// testPostQuantumKeyType creates a key of the given type and round-trips a plaintext through it.
+func testPostQuantumKeyType(t *testing.T, keyType string, algorithm kybertransit.Algorithm) {
+	t.Helper()
+	r := newTestRouter(t)
+	keyURL, _ := r.Get(routes.RouteNameCreateKey).URL("name", testKey1)
+	encURL, _ := r.Get(routes.RouteNameEncrypt).URL("name", testKey1)
+	decURL, _ := r.Get(routes.RouteNameDecrypt).URL("name", testKey1)
+
+	code, resp := doJSON(t, r, "POST", keyURL.String(), map[string]string{"type": keyType})
+	require.Equal(t, http.StatusCreated, code, resp)
+	assert.Equal(t, keyType, resp["type"])
+
+	code, enc := doJSON(t, r, "POST", encURL.String(), map[string]string{"plaintext": b64("fips 203")})
+	require.Equal(t, http.StatusOK, code, enc)
+	ciphertext := enc["ciphertext"].(string)
+	assert.True(t, strings.HasPrefix(ciphertext, keyType+":v1:"), "the key type must be visible in the token")
+	env, err := kybertransit.ParseEnvelope(ciphertext)
+	require.NoError(t, err)
+	assert.Equal(t, algorithm, env.Algorithm)
+
+	legacy := strings.Replace(ciphertext, keyType+":v1:", "kyber:v1:", 1)
+	for _, ct := range []string{ciphertext, legacy} {
+		code, resp = doJSON(t, r, "POST", decURL.String(), map[string]interface{}{"ciphertext": ct})
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, b64("fips 203"), resp["plaintext"])
+	}
+}
+
+func TestPlaintextEncoding(t *testing.T) {
+	r := newTestRouter(t)
+	keyURL, _ := r.Get(routes.RouteNameCreateKey).URL("name", testKey1)
+	encURL, _ := r.Get(routes.RouteNameEncrypt).URL("name", testKey1)
+	decURL, _ := r.Get(routes.RouteNameDecrypt).URL("name", testKey1)
+	doJSON(t, r, "POST", keyURL.String(), nil)
+
+	binary := base64.StdEncoding.EncodeToString([]byte{0x00, 0xff, 0xfe, 0x80, 0xc3, 0x28, 0x00})
+	code, binaryCT := doJSON(t, r, "POST", encURL.String(), map[string]string{"plaintext": binary})
+	require.Equal(t, http.StatusOK, code, binaryCT)
+	code, textCT := doJSON(t, r, "POST", encURL.String(), map[string]string{"plaintext": "héllo wörld", "encoding": "text"})
+	require.Equal(t, http.StatusOK, code, textCT)
+
+	encryptErrors := []struct {
+		name      string
+		body      map[string]string
+		wantError string
+	}{
+		{"invalid base64", map[string]string{"plaintext": "héllo"}, `Invalid plaintext: must be base64 (or set "encoding": "text")`},
+		{"unsupported encoding", map[string]string{"plaintext": "abc", "encoding": "hex"}, `Unsupported encoding "hex": must be "base64" or "text"`},
+	}
+	for _, tt := range encryptErrors {
+		t.Run(tt.name, func(t *testing.T) {
+			code, resp := doJSON(t, r, "POST", encURL.String(), tt.body)
+			assert.Equal(t, http.StatusBadRequest, code)
+			assert.Equal(t, tt.wantError, resp["error"])
+		})
+	}
+
+	tests := []struct {
+		name       string
+		ciphertext interface{}
+		encoding   string
+		wantStatus int
+		wantField  string
+		wantValue  string
+	}{
+		{"binary as base64", binaryCT["ciphertext"], "", http.StatusOK, "plaintext", binary},
+		{"binary as explicit base64", binaryCT["ciphertext"], "base64", http.StatusOK, "plaintext", binary},
+		{"binary as text", binaryCT["ciphertext"], "text", http.StatusBadRequest, "error", "Plaintext is not valid UTF-8: use base64 encoding"},
+		{"text as base64", textCT["ciphertext"], "", http.StatusOK, "plaintext", b64("héllo wörld")},
+		{"text as text", textCT["ciphertext"], "text", http.StatusOK, "plaintext", "héllo wörld"},
+		{"unsupported encoding", textCT["ciphertext"], "utf16", http.StatusBadRequest, "error", `Unsupported encoding "utf16": must be "base64" or "text"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, resp := doJSON(t, r, "POST", decURL.String(), map[string]interface{}{"ciphertext": tt.ciphertext, "encoding": tt.encoding})
+			assert.Equal(t, tt.wantStatus, code)
+			assert.Equal(t, tt.wantValue, resp[tt.wantField])
+		})
+	}
+}
+
+func TestAssociatedData(t *testing.T) {
+	r := newTestRouter(t)
+	keyURL, _ := r.Get(routes.RouteNameCreateKey).URL("name", testKey1)
+	rotateURL, _ := r.Get(routes.RouteNameRotateKey).URL("name", testKey1)
+	encURL, _ := r.Get(routes.RouteNameEncrypt).URL("name", testKey1)
+	decURL, _ := r.Get(routes.RouteNameDecrypt).URL("name", testKey1)
+	rewrapURL, _ := r.Get(routes.RouteNameRewrap).URL("name", testKey1)
+	_, created := doJSON(t, r, "POST", keyURL.String(), nil)
+	pubKey, _ := base64.StdEncoding.DecodeString(created["public_key"].(string))
+
+	customerA, customerB := b64("customers/a"), b64("customers/b")
+	code, enc := doJSON(t, r, "POST", encURL.String(), map[string]string{"plaintext": b64("4111 1111"), "associated_data": customerA})
+	require.Equal(t, http.StatusOK, code, enc)
+	legacyCT, legacyEnc, err := kybertransit.Encrypt(kybertransit.DefaultKeyType, pubKey, []byte("legacy"), nil)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		body       map[string]interface{}
+		wantStatus int
+		wantField  string
+		wantValue  string
+	}{
+		{"same associated_data", map[string]interface{}{"ciphertext": enc["ciphertext"], "associated_data": customerA}, http.StatusOK, "plaintext", b64("4111 1111")},
+		{"other associated_data", map[string]interface{}{"ciphertext": enc["ciphertext"], "associated_data": customerB}, http.StatusBadRequest, "error", "Decryption failed: ciphertext was modified or associated_data does not match"},
+		{"missing associated_data", map[string]interface{}{"ciphertext": enc["ciphertext"]}, http.StatusBadRequest, "error", "Decryption failed: ciphertext was modified or associated_data does not match"},
+		{"invalid associated_data", map[string]interface{}{"ciphertext": enc["ciphertext"], "associated_data": "!!!"}, http.StatusBadRequest, "error", "Invalid associated_data: must be base64"},
+		{"legacy form", map[string]interface{}{"ciphertext": legacyCT, "encdata": legacyEnc, "associated_data": customerA}, http.StatusBadRequest, "error", "associated_data is not supported with the legacy ciphertext+encdata form"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, resp := doJSON(t, r, "POST", decURL.String(), tt.body)
+			assert.Equal(t, tt.wantStatus, code)
+			assert.Equal(t, tt.wantValue, resp[tt.wantField])
+		})
+	}
+
+	code, resp := doJSON(t, r, "POST", encURL.String(), map[string]string{"plaintext": b64("x"), "associated_data": "!!!"})
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "Invalid associated_data: must be base64", resp["error"])
+
+	code, resp = doJSON(t, r, "POST", decURL.String(), map[string]interface{}{"batch_input": []map[string]interface{}{
+		{"ciphertext": enc["ciphertext"], "associated_data": customerA},
+		{"ciphertext": enc["ciphertext"], "associated_data": customerB},
+	}})
+	require.Equal(t, http.StatusOK, code, resp)
+	results := resp["batch_results"].([]interface{})
+	assert.Equal(t, b64("4111 1111"), results[0].(map[string]interface{})["plaintext"])
+	assert.Equal(t, "Decryption failed: ciphertext was modified or associated_data does not match", results[1].(map[string]interface{})["error"])
+
+	// Rewrap keeps the ciphertext bound to the same associated data.
+	doJSON(t, r, "POST", rotateURL.String(), nil)
+	code, rewrapped := doJSON(t, r, "POST", rewrapURL.String(), map[string]interface{}{"ciphertext": enc["ciphertext"], "associated_data": customerA})
+	require.Equal(t, http.StatusOK, code, rewrapped)
+	assert.Equal(t, float64(2), rewrapped["key_version"])
+	code, _ = doJSON(t, r, "POST", decURL.String(), map[string]interface{}{"ciphertext": rewrapped["ciphertext"]})
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, resp = doJSON(t, r, "POST", decURL.String(), map[string]interface{}{"ciphertext": rewrapped["ciphertext"], "associated_data": customerA})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, b64("4111 1111"), resp["plaintext"])
+}
+
+func TestDerivedKeys(t *testing.T) {
+	r := newTestRouter(t)
+	derivedURL, _ := r.Get(routes.RouteNameCreateKey).URL("name", testKey1)
+	plainURL, _ := r.Get(routes.RouteNameCreateKey).URL("name", testKey2)
+	signingURL, _ := r.Get(routes.RouteNameCreateKey).URL("name", testKey3)
+	encURL, _ := r.Get(routes.RouteNameEncrypt).URL("name", testKey1)
+	decURL, _ := r.Get(routes.RouteNameDecrypt).URL("name", testKey1)
+	rotateURL, _ := r.Get(routes.RouteNameRotateKey).URL("name", testKey1)
+	rewrapURL, _ := r.Get(routes.RouteNameRewrap).URL("name", testKey1)
+	dataKeyURL, _ := r.Get(routes.RouteNameDataKey).URL("type", "plaintext", "name", testKey1)
+	plainEncURL, _ := r.Get(routes.RouteNameEncrypt).URL("name", testKey2)
+
+	code, resp := doJSON(t, r, "POST", derivedURL.String(), map[string]interface{}{"type": "ml-kem-768", "derived": true})
+	require.Equal(t, http.StatusCreated, code, resp)
+	code, _ = doJSON(t, r, "POST", plainURL.String(), nil)
+	require.Equal(t, http.StatusCreated, code)
+	code, resp = doJSON(t, r, "POST", signingURL.String(), map[string]interface{}{"type": "ml-dsa-65", "derived": true})
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "Invalid key config: derived requires an encryption key type, not ml-dsa-65", resp["error"])
+	_, meta := doJSON(t, r, "GET", derivedURL.String(), nil)
+	assert.Equal(t, true, meta["derived"])
+
+	tenantA, tenantB := b64("tenant-a"), b64("tenant-b")
+	code, enc := doJSON(t, r, "POST", encURL.String(), map[string]string{"plaintext": b64("invoice"), "context": tenantA})
+	require.Equal(t, http.StatusOK, code, enc)
+
+	tests := []struct {
+		name       string
+		url        string
+		body       map[string]interface{}
+		wantStatus int
+		wantField  string
+		wantValue  string
+	}{
+		{"decrypt same context", decURL.String(), map[string]interface{}{"ciphertext": enc["ciphertext"], "context": tenantA}, http.StatusOK, "plaintext", b64("invoice")},
+		{"decrypt other context", decURL.String(), map[string]interface{}{"ciphertext": enc["ciphertext"], "context": tenantB}, http.StatusBadRequest, "error", "Decryption failed: ciphertext was modified or context or associated_data does not match"},
+		{"decrypt without context", decURL.String(), map[string]interface{}{"ciphertext": enc["ciphertext"]}, http.StatusBadRequest, "error", "Missing context: key is derived"},
+		{"encrypt without context", encURL.String(), map[string]interface{}{"plaintext": b64("x")}, http.StatusBadRequest, "error", "Missing context: key is derived"},
+		{"encrypt invalid context", encURL.String(), map[string]interface{}{"plaintext": b64("x"), "context": "!!!"}, http.StatusBadRequest, "error", "Invalid context: must be base64"},
+		{"context on non-derived key", plainEncURL.String(), map[string]interface{}{"plaintext": b64("x"), "context": tenantA}, http.StatusBadRequest, "error", "context is only supported for derived keys"},
+		{"data key without context", dataKeyURL.String(), nil, http.StatusBadRequest, "error", "Missing context: key is derived"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, resp := doJSON(t, r, "POST", tt.url, tt.body)
+			assert.Equal(t, tt.wantStatus, code)
+			assert.Equal(t, tt.wantValue, resp[tt.wantField])
+		})
+	}
+
+	code, dataKey := doJSON(t, r, "POST", dataKeyURL.String(), map[string]string{"context": tenantB})
+	require.Equal(t, http.StatusOK, code, dataKey)
+	code, resp = doJSON(t, r, "POST", decURL.String(), map[string]interface{}{"ciphertext": dataKey["ciphertext"], "context": tenantB})
+	require.Equal(t, http.StatusOK, code, resp)
+	assert.Equal(t, dataKey["plaintext"], resp["plaintext"])
+
+	doJSON(t, r, "POST", rotateURL.String(), nil)
+	code, rewrapped := doJSON(t, r, "POST", rewrapURL.String(), map[string]interface{}{"ciphertext": enc["ciphertext"], "context": tenantA})
+	require.Equal(t, http.StatusOK, code, rewrapped)
+	code, resp = doJSON(t, r, "POST", decURL.String(), map[string]interface{}{"ciphertext": rewrapped["ciphertext"], "context": tenantA})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, b64("invoice"), resp["plaintext"])
+}
+
+func TestConvergentEncryption(t *testing.T) {
+	r := newTestRouter(t)
+	convergentURL, _ := r.Get(routes.RouteNameCreateKey).URL("name", testKey1)
+	plainURL, _ := r.Get(routes.RouteNameCreateKey).URL("name", testKey2)
+	encURL, _ := r.Get(routes.RouteNameEncrypt).URL("name", testKey1)
+	decURL, _ := r.Get(routes.RouteNameDecrypt).URL("name", testKey1)
+	rotateURL, _ := r.Get(routes.RouteNameRotateKey).URL("name", testKey1)
+	rewrapURL, _ := r.Get(routes.RouteNameRewrap).URL("name", testKey1)
+
+	code, resp := doJSON(t, r, "POST", plainURL.String(), map[string]interface{}{"convergent_encryption": true})
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "Invalid key config: convergent_encryption requires derived", resp["error"])
+	code, resp = doJSON(t, r, "POST", convergentURL.String(), map[string]interface{}{"derived": true, "convergent_encryption": true})
+	require.Equal(t, http.StatusCreated, code, resp)
+	_, meta := doJSON(t, r, "GET", convergentURL.String(), nil)
+	assert.Equal(t, true, meta["convergent_encryption"])
+
+	encrypt := func(plaintext, context, associatedData string) string {
+		body := map[string]string{"plaintext": b64(plaintext), "context": b64(context)}
+		if associatedData != "" {
+			body["associated_data"] = b64(associatedData)
+		}
+		code, resp := doJSON(t, r, "POST", encURL.String(), body)
+		require.Equal(t, http.StatusOK, code, resp)
+		return resp["ciphertext"].(string)
+	}
+	base := encrypt("alice@example.com", "users", "")
+
+	tests := []struct {
+		name       string
+		ciphertext string
+		wantEqual  bool
+	}{
+		{"same plaintext and context", encrypt("alice@example.com", "users", ""), true},
+		{"other plaintext", encrypt("bob@example.com", "users", ""), false},
+		{"other context", encrypt("alice@example.com", "admins", ""), false},
+		{"other associated data", encrypt("alice@example.com", "users", "row-1"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.wantEqual {
+				assert.Equal(t, base, tt.ciphertext)
+			} else {
+				assert.NotEqual(t, base, tt.ciphertext)
+			}
+		})
+	}
+
+	code, resp = doJSON(t, r, "POST", decURL.String(), map[string]interface{}{"ciphertext": base, "context": b64("users")})
+	require.Equal(t, http.StatusOK, code, resp)
+	assert.Equal(t, b64("alice@example.com"), resp["plaintext"])
+
+	doJSON(t, r, "POST", rotateURL.String(), nil)
+	rewrap := func() string {
+		code, resp := doJSON(t, r, "POST", rewrapURL.String(), map[string]interface{}{"ciphertext": base, "context": b64("users")})
+		require.Equal(t, http.StatusOK, code, resp)
+		return resp["ciphertext"].(string)
+	}
+	rewrapped := rewrap()
+	assert.Equal(t, rewrapped, rewrap(), "rewrapping stays deterministic")
+	assert.Equal(t, encrypt("alice@example.com", "users", ""), rewrapped, "rewrapped ciphertext matches a fresh encryption")
+}
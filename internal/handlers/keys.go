@@ -0,0 +1,246 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	// defaultListLimit is the page size of GET /transit/keys when no limit is given.
+	defaultListLimit = 100
+	// maxListLimit is the largest page size GET /transit/keys accepts.
+	maxListLimit = 1000
+)
+
+// keyMetadata renders the public metadata of a key. Private keys are never included.
+func keyMetadata(key Key) map[string]interface{} {
+	versions := make([]map[string]interface{}, len(key.Versions))
+	for i, kv := range key.Versions {
+		versions[i] = map[string]interface{}{
+			"version":    kv.Version,
+			"created_at": kv.CreatedAt,
+			"public_key": base64.StdEncoding.EncodeToString(kv.KeyPair.PublicKey),
+		}
+	}
+	return map[string]interface{}{
+		"name":                   key.Name,
+		"type":                   key.Type,
+		"latest_version":         key.LatestVersion(),
+		"min_decryption_version": key.MinDecryptionVersion,
+		"min_encryption_version": key.MinEncryptionVersion,
+		"min_available_version":  key.MinAvailableVersion(),
+		"deletion_allowed":       key.DeletionAllowed,
+		"exportable":             key.Exportable,
+		"allow_plaintext_backup": key.AllowPlaintextBackup,
+		"auto_rotate_period":     key.AutoRotatePeriod.String(),
+		"derived":                key.Derived,
+		"convergent_encryption":  key.ConvergentEncryption,
+		"supports_encryption":    key.Type.SupportsEncryption(),
+		"supports_signing":       key.Type.SupportsSigning(),
+		"versions":               versions,
+	}
+}
+
+// ReadKeyHandler handles GET /transit/keys/{name}.
+// Returns 200 with the key type, configuration and the creation time and public key
+// of every version, 404 if key not found.
+func ReadKeyHandler(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	key, err := keyStoreManager.GetKey(name)
+	if errors.Is(err, ErrKeyNotFound) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "Key not found"})
+		return
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, keyMetadata(key))
+}
+
+// ConfigKeyHandler handles POST /transit/keys/{name}/config.
+// Updates the given fields of the key configuration: min_decryption_version,
+// min_encryption_version, deletion_allowed, exportable, allow_plaintext_backup and
+// auto_rotate_period ("0" disables auto rotation). Omitted fields keep their values.
+// exportable and allow_plaintext_backup cannot be disabled once enabled.
+// Returns 200 with the key metadata, 400 on invalid config, 404 if key not found.
+func ConfigKeyHandler(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+		return
+	}
+	var req struct {
+		MinDecryptionVersion *int    `json:"min_decryption_version"`
+		MinEncryptionVersion *int    `json:"min_encryption_version"`
+		DeletionAllowed      *bool   `json:"deletion_allowed"`
+		Exportable           *bool   `json:"exportable"`
+		AllowPlaintextBackup *bool   `json:"allow_plaintext_backup"`
+		AutoRotatePeriod     *string `json:"auto_rotate_period"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid JSON"})
+		return
+	}
+	var autoRotatePeriod *time.Duration
+	if req.AutoRotatePeriod != nil {
+		d, ok := parseAutoRotatePeriod(w, *req.AutoRotatePeriod)
+		if !ok {
+			return
+		}
+		autoRotatePeriod = &d
+	}
+	key, err := keyStoreManager.UpdateKeyConfig(name, func(c KeyConfig) KeyConfig {
+		c.MinDecryptionVersion = valueOr(req.MinDecryptionVersion, c.MinDecryptionVersion)
+		c.MinEncryptionVersion = valueOr(req.MinEncryptionVersion, c.MinEncryptionVersion)
+		c.DeletionAllowed = valueOr(req.DeletionAllowed, c.DeletionAllowed)
+		c.Exportable = valueOr(req.Exportable, c.Exportable)
+		c.AllowPlaintextBackup = valueOr(req.AllowPlaintextBackup, c.AllowPlaintextBackup)
+		c.AutoRotatePeriod = valueOr(autoRotatePeriod, c.AutoRotatePeriod)
+		return c
+	})
+	if errors.Is(err, ErrKeyNotFound) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "Key not found"})
+		return
+	}
+	if errors.Is(err, ErrInvalidKeyConfig) {
+		invalidKeyConfig(w, err)
+		return
+	}
+	if err != nil {
+		writeError(w, fmt.Errorf("failed to update key config: %w", err))
+		return
+	}
+	writeJSON(w, http.StatusOK, keyMetadata(key))
+}
+
+// TrimKeyHandler handles POST /transit/keys/{name}/trim.
+// Permanently deletes all versions older than "min_available_version", which may not
+// exceed min_decryption_version. Ciphertexts and signatures of trimmed versions can
+// no longer be decrypted or verified.
+// Returns 200 with the key metadata, 400 on an invalid version, 404 if key not found.
+func TrimKeyHandler(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+		return
+	}
+	var req struct {
+		MinAvailableVersion *int `json:"min_available_version"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid JSON"})
+		return
+	}
+	if req.MinAvailableVersion == nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Missing min_available_version"})
+		return
+	}
+	key, err := keyStoreManager.TrimKey(name, *req.MinAvailableVersion)
+	if errors.Is(err, ErrKeyNotFound) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "Key not found"})
+		return
+	}
+	if errors.Is(err, ErrInvalidKeyConfig) {
+		invalidKeyConfig(w, err)
+		return
+	}
+	if err != nil {
+		writeError(w, fmt.Errorf("failed to trim key: %w", err))
+		return
+	}
+	writeJSON(w, http.StatusOK, keyMetadata(key))
+}
+
+// invalidKeyConfig writes the 400 body for an error wrapping ErrInvalidKeyConfig.
+func invalidKeyConfig(w http.ResponseWriter, err error) {
+	writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid key config: " + strings.TrimPrefix(err.Error(), ErrInvalidKeyConfig.Error()+": ")})
+}
+
+// parseAutoRotatePeriod parses an auto_rotate_period duration string such as "720h".
+// An empty string disables auto rotation. On failure it writes a 400 response and
+// returns false.
+func parseAutoRotatePeriod(w http.ResponseWriter, s string) (time.Duration, bool) {
+	if s == "" {
+		return 0, true
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": `Invalid auto_rotate_period: must be a duration such as "720h"`})
+		return 0, false
+	}
+	return d, true
+}
+
+// valueOr returns *p, or fallback if p is nil.
+func valueOr[T any](p *T, fallback T) T {
+	if p == nil {
+		return fallback
+	}
+	return *p
+}
+
+// ListKeysHandler handles GET /transit/keys.
+// Returns key names in sorted order, at most "limit" (default 100, max 1000) per page,
+// starting after the name given in "after". When more keys follow, "next_after" holds
+// the value to pass as "after" for the next page.
+// Returns 200 on success, 400 on invalid limit.
+func ListKeysHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	limit := defaultListLimit
+	if s := query.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxListLimit {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("Invalid limit: must be between 1 and %d", maxListLimit)})
+			return
+		}
+		limit = n
+	}
+	names, err := keyStoreManager.ListKeys()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	after := query.Get("after")
+	start := sort.Search(len(names), func(i int) bool { return names[i] > after })
+	page := names[start:min(start+limit, len(names))]
+	resp := map[string]interface{}{"keys": page}
+	if start+len(page) < len(names) {
+		resp["next_after"] = page[len(page)-1]
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// DeleteKeyHandler handles DELETE /transit/keys/{name}.
+// Permanently removes the key with all of its versions; the key must have been
+// configured with deletion_allowed.
+// Returns 204 on success, 400 if deletion is not allowed, 404 if key not found.
+func DeleteKeyHandler(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	err := keyStoreManager.DeleteKey(name)
+	if errors.Is(err, ErrKeyNotFound) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "Key not found"})
+		return
+	}
+	if errors.Is(err, ErrDeletionNotAllowed) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Deletion is not allowed for this key: set deletion_allowed first"})
+		return
+	}
+	if err != nil {
+		writeError(w, fmt.Errorf("failed to delete key: %w", err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,249 @@
+package handlers_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/dezween/ElevexaCodingChallenge2/internal/routes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadKeyHandler(t *testing.T) {
+	r := newTestRouter(t)
+	keyURL, _ := r.Get(routes.RouteNameCreateKey).URL("name", testKey1)
+	rotateURL, _ := r.Get(routes.RouteNameRotateKey).URL("name", testKey1)
+	_, created := doJSON(t, r, "POST", keyURL.String(), map[string]string{"type": "ml-kem-512"})
+	_, rotated := doJSON(t, r, "POST", rotateURL.String(), nil)
+
+	code, resp := doJSON(t, r, "GET", keyURL.String(), nil)
+	require.Equal(t, http.StatusOK, code, resp)
+	assert.Equal(t, testKey1, resp["name"])
+	assert.Equal(t, "ml-kem-512", resp["type"])
+	assert.Equal(t, float64(2), resp["latest_version"])
+	assert.Equal(t, false, resp["deletion_allowed"])
+	assert.Equal(t, true, resp["supports_encryption"])
+	assert.Equal(t, false, resp["supports_signing"])
+	versions := resp["versions"].([]interface{})
+	require.Len(t, versions, 2)
+	v1 := versions[0].(map[string]interface{})
+	assert.Equal(t, float64(1), v1["version"])
+	assert.Equal(t, created["public_key"], v1["public_key"])
+	assert.NotEmpty(t, v1["created_at"])
+	assert.Equal(t, rotated["public_key"], versions[1].(map[string]interface{})["public_key"])
+	assert.NotContains(t, v1, "private_key")
+
+	unknownURL, _ := r.Get(routes.RouteNameReadKey).URL("name", unknownKey)
+	code, resp = doJSON(t, r, "GET", unknownURL.String(), nil)
+	assert.Equal(t, http.StatusNotFound, code)
+	assert.Equal(t, "Key not found", resp["error"])
+}
+
+func TestListKeysHandler(t *testing.T) {
+	r := newTestRouter(t)
+	listURL, _ := r.Get(routes.RouteNameListKeys).URL()
+	code, resp := doJSON(t, r, "GET", listURL.String(), nil)
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []interface{}{}, resp["keys"])
+
+	for _, name := range []string{"c", "a", "e", "b", "d"} {
+		keyURL, _ := r.Get(routes.RouteNameCreateKey).URL("name", name)
+		code, _ := doJSON(t, r, "POST", keyURL.String(), nil)
+		require.Equal(t, http.StatusCreated, code)
+	}
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantKeys   []interface{}
+		wantNext   interface{}
+	}{
+		{"all", "", http.StatusOK, []interface{}{"a", "b", "c", "d", "e"}, nil},
+		{"first page", "?limit=2", http.StatusOK, []interface{}{"a", "b"}, "b"},
+		{"second page", "?limit=2&after=b", http.StatusOK, []interface{}{"c", "d"}, "d"},
+		{"last page", "?limit=2&after=d", http.StatusOK, []interface{}{"e"}, nil},
+		{"after a missing name", "?after=bb", http.StatusOK, []interface{}{"c", "d", "e"}, nil},
+		{"past the end", "?after=z", http.StatusOK, []interface{}{}, nil},
+		{"invalid limit", "?limit=0", http.StatusBadRequest, nil, nil},
+		{"limit too large", "?limit=1001", http.StatusBadRequest, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, resp := doJSON(t, r, "GET", listURL.String()+tt.query, nil)
+			assert.Equal(t, tt.wantStatus, code)
+			if tt.wantStatus != http.StatusOK {
+				assert.Contains(t, resp["error"], "Invalid limit")
+				return
+			}
+			assert.Equal(t, tt.wantKeys, resp["keys"])
+			assert.Equal(t, tt.wantNext, resp["next_after"])
+		})
+	}
+}
+
+func TestDeleteKeyHandler(t *testing.T) {
+	r := newTestRouter(t)
+	protectedURL, _ := r.Get(routes.RouteNameCreateKey).URL("name", testKey1)
+	deletableURL, _ := r.Get(routes.RouteNameCreateKey).URL("name", testKey2)
+	doJSON(t, r, "POST", protectedURL.String(), nil)
+	code, resp := doJSON(t, r, "POST", deletableURL.String(), map[string]interface{}{"deletion_allowed": true})
+	require.Equal(t, http.StatusCreated, code, resp)
+
+	code, resp = doJSON(t, r, "DELETE", protectedURL.String(), nil)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "Deletion is not allowed for this key: set deletion_allowed first", resp["error"])
+	code, _ = doJSON(t, r, "GET", protectedURL.String(), nil)
+	assert.Equal(t, http.StatusOK, code)
+
+	code, _ = doJSON(t, r, "DELETE", deletableURL.String(), nil)
+	assert.Equal(t, http.StatusNoContent, code)
+	code, _ = doJSON(t, r, "GET", deletableURL.String(), nil)
+	assert.Equal(t, http.StatusNotFound, code)
+	code, resp = doJSON(t, r, "DELETE", deletableURL.String(), nil)
+	assert.Equal(t, http.StatusNotFound, code)
+	assert.Equal(t, "Key not found", resp["error"])
+
+	// The name can be reused for a new, unrelated key.
+	code, _ = doJSON(t, r, "POST", deletableURL.String(), nil)
+	require.Equal(t, http.StatusCreated, code)
+
+	listURL, _ := r.Get(routes.RouteNameListKeys).URL()
+	_, resp = doJSON(t, r, "GET", listURL.String(), nil)
+	assert.Equal(t, []interface{}{testKey1, testKey2}, resp["keys"])
+}
+
+func TestConfigKeyHandler(t *testing.T) {
+	r := newTestRouter(t)
+	keyURL, _ := r.Get(routes.RouteNameCreateKey).URL("name", testKey1)
+	rotateURL, _ := r.Get(routes.RouteNameRotateKey).URL("name", testKey1)
+	configURL, _ := r.Get(routes.RouteNameKeyConfig).URL("name", testKey1)
+	encURL, _ := r.Get(routes.RouteNameEncrypt).URL("name", testKey1)
+	decURL, _ := r.Get(routes.RouteNameDecrypt).URL("name", testKey1)
+
+	doJSON(t, r, "POST", keyURL.String(), nil)
+	_, v1 := doJSON(t, r, "POST", encURL.String(), map[string]string{"plaintext": b64("old")})
+	doJSON(t, r, "POST", rotateURL.String(), nil)
+	doJSON(t, r, "POST", rotateURL.String(), nil)
+
+	tests := []struct {
+		name       string
+		body       map[string]interface{}
+		wantStatus int
+		wantError  string
+	}{
+		{"min_decryption_version zero", map[string]interface{}{"min_decryption_version": 0}, http.StatusBadRequest, "Invalid key config: min_decryption_version must be between 1 and the latest version 3"},
+		{"min_decryption_version too high", map[string]interface{}{"min_decryption_version": 4}, http.StatusBadRequest, "Invalid key config: min_decryption_version must be between 1 and the latest version 3"},
+		{"min_encryption_version below min_decryption_version", map[string]interface{}{"min_decryption_version": 2, "min_encryption_version": 1}, http.StatusBadRequest, "Invalid key config: min_encryption_version must be 0 or between min_decryption_version 2 and the latest version 3"},
+		{"valid", map[string]interface{}{"min_decryption_version": 2, "min_encryption_version": 3, "exportable": true}, http.StatusOK, ""},
+		{"disable exportable", map[string]interface{}{"exportable": false}, http.StatusBadRequest, "Invalid key config: exportable cannot be disabled once enabled"},
+		{"auto_rotate_period too short", map[string]interface{}{"auto_rotate_period": "30m"}, http.StatusBadRequest, "Invalid key config: auto_rotate_period must be 0 (disabled) or at least 1h0m0s"},
+		{"auto_rotate_period not a duration", map[string]interface{}{"auto_rotate_period": "monthly"}, http.StatusBadRequest, `Invalid auto_rotate_period: must be a duration such as "720h"`},
+		{"auto_rotate_period", map[string]interface{}{"auto_rotate_period": "720h"}, http.StatusOK, ""},
+		{"invalid JSON", nil, http.StatusBadRequest, "Invalid JSON"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body interface{} = tt.body
+			if tt.body == nil {
+				body = "notjson"
+			}
+			code, resp := doJSON(t, r, "POST", configURL.String(), body)
+			assert.Equal(t, tt.wantStatus, code)
+			if tt.wantError != "" {
+				assert.Equal(t, tt.wantError, resp["error"])
+			}
+		})
+	}
+
+	code, resp := doJSON(t, r, "GET", keyURL.String(), nil)
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, float64(2), resp["min_decryption_version"])
+	assert.Equal(t, float64(3), resp["min_encryption_version"])
+	assert.Equal(t, true, resp["exportable"])
+	assert.Equal(t, false, resp["deletion_allowed"])
+	assert.Equal(t, "720h0m0s", resp["auto_rotate_period"])
+
+	enforced := []struct {
+		name       string
+		url        string
+		body       map[string]interface{}
+		wantStatus int
+		wantError  string
+	}{
+		{"decrypt retired version", decURL.String(), map[string]interface{}{"ciphertext": v1["ciphertext"]}, http.StatusBadRequest, "Key version 1 is below min_decryption_version 2"},
+		{"encrypt below min_encryption_version", encURL.String(), map[string]interface{}{"plaintext": b64("a"), "key_version": 2}, http.StatusBadRequest, "Key version 2 is below min_encryption_version 3"},
+		{"encrypt with allowed version", encURL.String(), map[string]interface{}{"plaintext": b64("a"), "key_version": 3}, http.StatusOK, ""},
+		{"encrypt with latest", encURL.String(), map[string]interface{}{"plaintext": b64("a")}, http.StatusOK, ""},
+	}
+	for _, tt := range enforced {
+		t.Run(tt.name, func(t *testing.T) {
+			code, resp := doJSON(t, r, "POST", tt.url, tt.body)
+			assert.Equal(t, tt.wantStatus, code)
+			if tt.wantError != "" {
+				assert.Equal(t, tt.wantError, resp["error"])
+			}
+		})
+	}
+
+	unknownURL, _ := r.Get(routes.RouteNameKeyConfig).URL("name", unknownKey)
+	code, _ = doJSON(t, r, "POST", unknownURL.String(), map[string]interface{}{})
+	assert.Equal(t, http.StatusNotFound, code)
+}
+
+func TestTrimKeyHandler(t *testing.T) {
+	r := newTestRouter(t)
+	keyURL, _ := r.Get(routes.RouteNameCreateKey).URL("name", testKey1)
+	rotateURL, _ := r.Get(routes.RouteNameRotateKey).URL("name", testKey1)
+	configURL, _ := r.Get(routes.RouteNameKeyConfig).URL("name", testKey1)
+	trimURL, _ := r.Get(routes.RouteNameTrimKey).URL("name", testKey1)
+	encURL, _ := r.Get(routes.RouteNameEncrypt).URL("name", testKey1)
+	decURL, _ := r.Get(routes.RouteNameDecrypt).URL("name", testKey1)
+
+	doJSON(t, r, "POST", keyURL.String(), nil)
+	_, v1 := doJSON(t, r, "POST", encURL.String(), map[string]string{"plaintext": b64("old")})
+	doJSON(t, r, "POST", rotateURL.String(), nil)
+	_, v2 := doJSON(t, r, "POST", encURL.String(), map[string]string{"plaintext": b64("current")})
+	doJSON(t, r, "POST", rotateURL.String(), nil)
+
+	tests := []struct {
+		name       string
+		body       interface{}
+		wantStatus int
+		wantError  string
+	}{
+		{"past min_decryption_version", map[string]int{"min_available_version": 2}, http.StatusBadRequest, "Invalid key config: min_available_version must be between the oldest available version 1 and min_decryption_version 1"},
+		{"missing version", map[string]int{}, http.StatusBadRequest, "Missing min_available_version"},
+		{"invalid JSON", "notjson", http.StatusBadRequest, "Invalid JSON"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, resp := doJSON(t, r, "POST", trimURL.String(), tt.body)
+			assert.Equal(t, tt.wantStatus, code)
+			assert.Equal(t, tt.wantError, resp["error"])
+		})
+	}
+
+	code, resp := doJSON(t, r, "POST", configURL.String(), map[string]int{"min_decryption_version": 2})
+	require.Equal(t, http.StatusOK, code, resp)
+	code, resp = doJSON(t, r, "POST", trimURL.String(), map[string]int{"min_available_version": 2})
+	require.Equal(t, http.StatusOK, code, resp)
+	assert.Equal(t, float64(2), resp["min_available_version"])
+	assert.Equal(t, float64(3), resp["latest_version"])
+	assert.Len(t, resp["versions"], 2)
+
+	code, resp = doJSON(t, r, "POST", decURL.String(), map[string]interface{}{"ciphertext": v1["ciphertext"]})
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "Key version 1 is below min_decryption_version 2", resp["error"])
+	code, resp = doJSON(t, r, "POST", decURL.String(), map[string]interface{}{"ciphertext": v2["ciphertext"]})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, b64("current"), resp["plaintext"])
+
+	code, resp = doJSON(t, r, "POST", configURL.String(), map[string]int{"min_decryption_version": 1})
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "Invalid key config: min_decryption_version must be between 2 and the latest version 3", resp["error"])
+
+	unknownURL, _ := r.Get(routes.RouteNameTrimKey).URL("name", unknownKey)
+	code, _ = doJSON(t, r, "POST", unknownURL.String(), map[string]int{"min_available_version": 1})
+	assert.Equal(t, http.StatusNotFound, code)
+}
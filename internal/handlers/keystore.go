@@ -0,0 +1,401 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dezween/ElevexaCodingChallenge2/internal/kybertransit"
+	"github.com/dezween/ElevexaCodingChallenge2/internal/storage"
+)
+
+// keyPrefix is the storage path prefix under which named keys are persisted.
+const keyPrefix = "keys/"
+
+var (
+	// ErrKeyNotFound is returned when a named key does not exist.
+	ErrKeyNotFound = errors.New("key not found")
+	// ErrDeletionNotAllowed is returned when deleting a key whose deletion_allowed flag is not set.
+	ErrDeletionNotAllowed = errors.New("key deletion is not allowed")
+	// ErrInvalidKeyConfig is returned when a key configuration is inconsistent with the key.
+	ErrInvalidKeyConfig = errors.New("invalid key config")
+)
+
+// KeyVersion is a single version of a named key.
+type KeyVersion struct {
+	Version   int                  `json:"version"`    // Version number, starting at 1
+	KeyPair   kybertransit.KeyPair `json:"key_pair"`   // Key pair of this version, of the key's type
+	CreatedAt time.Time            `json:"created_at"` // Creation time of this version
+}
+
+// KeyConfig holds the settings of a key that can be changed after creation.
+type KeyConfig struct {
+	MinDecryptionVersion int  `json:"min_decryption_version"` // Oldest version allowed to decrypt and verify
+	MinEncryptionVersion int  `json:"min_encryption_version"` // Oldest version a caller may request for encryption; 0 allows any
+	DeletionAllowed      bool `json:"deletion_allowed"`       // Whether DeleteKey may remove the key
+	Exportable           bool `json:"exportable"`             // Whether private keys may be exported; cannot be unset
+	AllowPlaintextBackup bool `json:"allow_plaintext_backup"` // Whether the key may be backed up in plaintext (with exportable); cannot be unset
+
+	AutoRotatePeriod time.Duration `json:"auto_rotate_period"` // Rotate once the latest version is this old; 0 disables
+
+	Derived              bool `json:"derived"`               // Whether every encryption needs a context the DEM key is derived from; set at creation only
+	ConvergentEncryption bool `json:"convergent_encryption"` // Whether encryption is deterministic per plaintext and context; requires derived, set at creation only
+}
+
+// minAutoRotatePeriod is the shortest auto_rotate_period accepted.
+const minAutoRotatePeriod = time.Hour
+
+// Key is a named key holding an ordered list of versions, oldest first.
+// Encryption always uses the latest version; decryption selects the version
+// recorded in the ciphertext. All versions share the key type chosen at creation.
+type Key struct {
+	Name string               `json:"name"`
+	Type kybertransit.KeyType `json:"type"`
+	KeyConfig
+	Versions []KeyVersion `json:"versions"`
+}
+
+// LatestVersion returns the number of the newest version.
+func (k Key) LatestVersion() int {
+	return k.Versions[len(k.Versions)-1].Version
+}
+
+// Latest returns the newest version of the key.
+func (k Key) Latest() KeyVersion {
+	return k.Versions[len(k.Versions)-1]
+}
+
+// MinAvailableVersion returns the number of the oldest version that has not been trimmed.
+func (k Key) MinAvailableVersion() int {
+	return k.Versions[0].Version
+}
+
+// validateConfig checks that config can be applied to the key.
+// Min versions must name available versions, min_encryption_version may not be below
+// min_decryption_version, exportable and allow_plaintext_backup cannot be unset, and
+// derived and convergent_encryption are fixed at creation; derived requires an encryption
+// key type and convergent_encryption requires derived.
+func (k Key) validateConfig(config KeyConfig) error {
+	latest, oldest := k.LatestVersion(), k.MinAvailableVersion()
+	if config.MinDecryptionVersion < oldest || config.MinDecryptionVersion > latest {
+		return fmt.Errorf("%w: min_decryption_version must be between %d and the latest version %d", ErrInvalidKeyConfig, oldest, latest)
+	}
+	if config.MinEncryptionVersion != 0 && (config.MinEncryptionVersion < config.MinDecryptionVersion || config.MinEncryptionVersion > latest) {
+		return fmt.Errorf("%w: min_encryption_version must be 0 or between min_decryption_version %d and the latest version %d",
+			ErrInvalidKeyConfig, config.MinDecryptionVersion, latest)
+	}
+	if k.Exportable && !config.Exportable {
+		return fmt.Errorf("%w: exportable cannot be disabled once enabled", ErrInvalidKeyConfig)
+	}
+	if k.AllowPlaintextBackup && !config.AllowPlaintextBackup {
+		return fmt.Errorf("%w: allow_plaintext_backup cannot be disabled once enabled", ErrInvalidKeyConfig)
+	}
+	if k.Derived != config.Derived {
+		return fmt.Errorf("%w: derived cannot be changed after creation", ErrInvalidKeyConfig)
+	}
+	if config.Derived && !k.Type.SupportsEncryption() {
+		return fmt.Errorf("%w: derived requires an encryption key type, not %s", ErrInvalidKeyConfig, k.Type)
+	}
+	if k.ConvergentEncryption != config.ConvergentEncryption {
+		return fmt.Errorf("%w: convergent_encryption cannot be changed after creation", ErrInvalidKeyConfig)
+	}
+	if config.ConvergentEncryption && !config.Derived {
+		return fmt.Errorf("%w: convergent_encryption requires derived", ErrInvalidKeyConfig)
+	}
+	if config.AutoRotatePeriod != 0 && config.AutoRotatePeriod < minAutoRotatePeriod {
+		return fmt.Errorf("%w: auto_rotate_period must be 0 (disabled) or at least %s", ErrInvalidKeyConfig, minAutoRotatePeriod)
+	}
+	return nil
+}
+
+// rotationDue reports whether auto rotation is enabled for the key and its latest
+// version is at least auto_rotate_period old at now. The creation time of the latest
+// version is persisted with the key, so the schedule survives restarts.
+func (k Key) rotationDue(now time.Time) bool {
+	return k.AutoRotatePeriod > 0 && !now.Before(k.Latest().CreatedAt.Add(k.AutoRotatePeriod))
+}
+
+// Version returns the given version of the key, if present.
+func (k Key) Version(v int) (KeyVersion, bool) {
+	for _, kv := range k.Versions {
+		if kv.Version == v {
+			return kv, true
+		}
+	}
+	return KeyVersion{}, false
+}
+
+// KeyStoreManager manages versioned Kyber keys on top of a Storage backend.
+// Each key is persisted as a JSON document at "keys/<name>". Writes are serialized
+// so read-modify-write operations such as rotation are atomic.
+type KeyStoreManager struct {
+	storage storage.Storage
+	mu      sync.RWMutex
+}
+
+// NewKeyStoreManager creates a key store manager backed by the given storage.
+func NewKeyStoreManager(s storage.Storage) *KeyStoreManager {
+	return &KeyStoreManager{storage: s}
+}
+
+// CreateKey creates a new key with the given name, type and configuration and a single version 1.
+// The boolean result reports whether the key already existed. Returns an error wrapping
+// ErrInvalidKeyConfig if the configuration is rejected.
+func (m *KeyStoreManager) CreateKey(name string, keyType kybertransit.KeyType, config KeyConfig) (Key, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, err := m.load(name)
+	if err == nil {
+		return Key{}, true, nil
+	}
+	if !errors.Is(err, ErrKeyNotFound) {
+		return Key{}, false, err
+	}
+	kv, err := newKeyVersion(keyType, 1)
+	if err != nil {
+		return Key{}, false, err
+	}
+	config.MinDecryptionVersion = 1
+	config.MinEncryptionVersion = 0
+	key := Key{Name: name, Type: keyType, KeyConfig: config, Versions: []KeyVersion{kv}}
+	if err := key.validateConfig(config); err != nil {
+		return Key{}, false, err
+	}
+	if err := m.save(key); err != nil {
+		return Key{}, false, err
+	}
+	return key, false, nil
+}
+
+// RestoreKey stores key, typically decoded from a backup, under the given name with
+// all of its versions and configuration. The boolean result reports whether a key with
+// that name already existed, in which case nothing is written. Returns an error wrapping
+// ErrInvalidKeyConfig if the key is malformed or its configuration is rejected.
+func (m *KeyStoreManager) RestoreKey(name string, key Key) (Key, bool, error) {
+	if _, err := kybertransit.ParseKeyType(string(key.Type)); err != nil || key.Type == "" {
+		return Key{}, false, fmt.Errorf("%w: unsupported key type %q", ErrInvalidKeyConfig, key.Type)
+	}
+	if len(key.Versions) == 0 {
+		return Key{}, false, fmt.Errorf("%w: key has no versions", ErrInvalidKeyConfig)
+	}
+	for i, kv := range key.Versions {
+		if kv.Version < 1 || (i > 0 && kv.Version <= key.Versions[i-1].Version) {
+			return Key{}, false, fmt.Errorf("%w: versions must be positive and ascending", ErrInvalidKeyConfig)
+		}
+		if len(kv.KeyPair.PublicKey) == 0 || len(kv.KeyPair.PrivateKey) == 0 {
+			return Key{}, false, fmt.Errorf("%w: version %d is missing its key pair", ErrInvalidKeyConfig, kv.Version)
+		}
+	}
+	if err := key.validateConfig(key.KeyConfig); err != nil {
+		return Key{}, false, err
+	}
+	key.Name = name
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, err := m.load(name)
+	if err == nil {
+		return Key{}, true, nil
+	}
+	if !errors.Is(err, ErrKeyNotFound) {
+		return Key{}, false, err
+	}
+	if err := m.save(key); err != nil {
+		return Key{}, false, err
+	}
+	return key, false, nil
+}
+
+// GetKey returns the named key with all of its versions, or ErrKeyNotFound.
+func (m *KeyStoreManager) GetKey(name string) (Key, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.load(name)
+}
+
+// RotateKey appends a new version to the named key and returns the updated key.
+// Returns ErrKeyNotFound if the key does not exist.
+func (m *KeyStoreManager) RotateKey(name string) (Key, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key, err := m.load(name)
+	if err != nil {
+		return Key{}, err
+	}
+	return m.rotate(key)
+}
+
+// RotateDueKeys rotates every key whose auto_rotate_period has elapsed at now and
+// returns the rotated keys. A key that fails to rotate does not stop the others;
+// all failures are returned joined.
+func (m *KeyStoreManager) RotateDueKeys(now time.Time) ([]Key, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	paths, err := m.storage.List(keyPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("keystore: failed to list keys: %w", err)
+	}
+	var (
+		rotated []Key
+		errs    []error
+	)
+	for _, p := range paths {
+		key, err := m.load(strings.TrimPrefix(p, keyPrefix))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !key.rotationDue(now) {
+			continue
+		}
+		key, err = m.rotate(key)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		rotated = append(rotated, key)
+	}
+	return rotated, errors.Join(errs...)
+}
+
+// rotate appends a new version to key and stores it. The caller must hold m.mu.
+func (m *KeyStoreManager) rotate(key Key) (Key, error) {
+	kv, err := newKeyVersion(key.Type, key.LatestVersion()+1)
+	if err != nil {
+		return Key{}, err
+	}
+	key.Versions = append(key.Versions, kv)
+	if err := m.save(key); err != nil {
+		return Key{}, err
+	}
+	return key, nil
+}
+
+// UpdateKeyConfig applies update to the configuration of the named key and stores the
+// result if it passes validation. Returns ErrKeyNotFound if the key does not exist and
+// an error wrapping ErrInvalidKeyConfig if the new configuration is rejected.
+func (m *KeyStoreManager) UpdateKeyConfig(name string, update func(KeyConfig) KeyConfig) (Key, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key, err := m.load(name)
+	if err != nil {
+		return Key{}, err
+	}
+	config := update(key.KeyConfig)
+	if err := key.validateConfig(config); err != nil {
+		return Key{}, err
+	}
+	key.KeyConfig = config
+	if err := m.save(key); err != nil {
+		return Key{}, err
+	}
+	return key, nil
+}
+
+// TrimKey permanently deletes all versions of the named key older than minAvailable,
+// including their private keys, and returns the updated key. minAvailable may not
+// exceed min_decryption_version, so only versions that can no longer decrypt or verify
+// are removed. Returns ErrKeyNotFound if the key does not exist and an error wrapping
+// ErrInvalidKeyConfig if minAvailable is out of range.
+func (m *KeyStoreManager) TrimKey(name string, minAvailable int) (Key, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key, err := m.load(name)
+	if err != nil {
+		return Key{}, err
+	}
+	if minAvailable < key.MinAvailableVersion() || minAvailable > key.MinDecryptionVersion {
+		return Key{}, fmt.Errorf("%w: min_available_version must be between the oldest available version %d and min_decryption_version %d",
+			ErrInvalidKeyConfig, key.MinAvailableVersion(), key.MinDecryptionVersion)
+	}
+	key.Versions = slices.DeleteFunc(key.Versions, func(kv KeyVersion) bool {
+		return kv.Version < minAvailable
+	})
+	if err := m.save(key); err != nil {
+		return Key{}, err
+	}
+	return key, nil
+}
+
+// ListKeys returns the names of all keys, sorted.
+func (m *KeyStoreManager) ListKeys() ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	paths, err := m.storage.List(keyPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("keystore: failed to list keys: %w", err)
+	}
+	names := make([]string, len(paths))
+	for i, p := range paths {
+		names[i] = strings.TrimPrefix(p, keyPrefix)
+	}
+	return names, nil
+}
+
+// DeleteKey permanently removes the named key with all of its versions.
+// Returns ErrKeyNotFound if the key does not exist and ErrDeletionNotAllowed
+// unless the key's deletion_allowed flag is set.
+func (m *KeyStoreManager) DeleteKey(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key, err := m.load(name)
+	if err != nil {
+		return err
+	}
+	if !key.DeletionAllowed {
+		return ErrDeletionNotAllowed
+	}
+	if err := m.storage.Delete(keyPrefix + name); err != nil {
+		return fmt.Errorf("keystore: failed to delete key %q: %w", name, err)
+	}
+	return nil
+}
+
+// load reads and decodes the named key from storage.
+func (m *KeyStoreManager) load(name string) (Key, error) {
+	data, err := m.storage.Get(keyPrefix + name)
+	if errors.Is(err, storage.ErrNotFound) {
+		return Key{}, ErrKeyNotFound
+	}
+	if err != nil {
+		return Key{}, fmt.Errorf("keystore: failed to read key %q: %w", name, err)
+	}
+	var key Key
+	if err := json.Unmarshal(data, &key); err != nil {
+		return Key{}, fmt.Errorf("keystore: failed to decode key %q: %w", name, err)
+	}
+	if len(key.Versions) == 0 {
+		return Key{}, fmt.Errorf("keystore: key %q has no versions", name)
+	}
+	if key.Type == "" {
+		key.Type = kybertransit.DefaultKeyType
+	}
+	if key.MinDecryptionVersion == 0 {
+		key.MinDecryptionVersion = 1
+	}
+	return key, nil
+}
+
+// save encodes and writes the key to storage.
+func (m *KeyStoreManager) save(key Key) error {
+	data, err := json.Marshal(key)
+	if err != nil {
+		return fmt.Errorf("keystore: failed to encode key %q: %w", key.Name, err)
+	}
+	if err := m.storage.Put(keyPrefix+key.Name, data); err != nil {
+		return fmt.Errorf("keystore: failed to write key %q: %w", key.Name, err)
+	}
+	return nil
+}
+
+// newKeyVersion generates a fresh key pair of the given type for the given version number.
+func newKeyVersion(keyType kybertransit.KeyType, version int) (KeyVersion, error) {
+	kp, err := kybertransit.GenerateKeyPair(keyType)
+	if err != nil {
+		return KeyVersion{}, err
+	}
+	return KeyVersion{Version: version, KeyPair: kp, CreatedAt: time.Now().UTC()}, nil
+}
@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dezween/ElevexaCodingChallenge2/internal/kybertransit"
+	"github.com/dezween/ElevexaCodingChallenge2/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyStoreManager_PersistsAcrossRestart(t *testing.T) {
+	dir := t.TempDir()
+	backend, err := storage.NewFile(dir)
+	require.NoError(t, err)
+	m := NewKeyStoreManager(backend)
+
+	created, exists, err := m.CreateKey("persistent", kybertransit.KeyTypeKyber768, KeyConfig{})
+	require.NoError(t, err)
+	assert.False(t, exists)
+	rotated, err := m.RotateKey("persistent")
+	require.NoError(t, err)
+	assert.Equal(t, 2, rotated.LatestVersion())
+
+	// Simulate a restart by opening a new manager on the same directory.
+	reopened, err := storage.NewFile(dir)
+	require.NoError(t, err)
+	m = NewKeyStoreManager(reopened)
+
+	key, err := m.GetKey("persistent")
+	require.NoError(t, err)
+	assert.Equal(t, 2, key.LatestVersion())
+	assert.Equal(t, kybertransit.KeyTypeKyber768, key.Type)
+	v1, ok := key.Version(1)
+	require.True(t, ok)
+	assert.Equal(t, created.Latest().KeyPair, v1.KeyPair)
+	assert.Equal(t, rotated.Latest().KeyPair, key.Latest().KeyPair)
+
+	_, exists, err = m.CreateKey("persistent", kybertransit.KeyTypeKyber768, KeyConfig{})
+	require.NoError(t, err)
+	assert.True(t, exists)
+}
+
+func TestKeyStoreManager_Errors(t *testing.T) {
+	m := NewKeyStoreManager(storage.NewMemory())
+
+	_, err := m.GetKey("missing")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+	_, err = m.RotateKey("missing")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+}
+
+func TestKeyStoreManager_ListAndDelete(t *testing.T) {
+	m := NewKeyStoreManager(storage.NewMemory())
+	_, _, err := m.CreateKey("b", kybertransit.DefaultKeyType, KeyConfig{})
+	require.NoError(t, err)
+	_, _, err = m.CreateKey("a", kybertransit.DefaultKeyType, KeyConfig{DeletionAllowed: true})
+	require.NoError(t, err)
+
+	names, err := m.ListKeys()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, names)
+
+	assert.ErrorIs(t, m.DeleteKey("b"), ErrDeletionNotAllowed)
+	assert.ErrorIs(t, m.DeleteKey("missing"), ErrKeyNotFound)
+	require.NoError(t, m.DeleteKey("a"))
+	_, err = m.GetKey("a")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+
+	names, err = m.ListKeys()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b"}, names)
+}
+
+func TestKeyStoreManager_UpdateKeyConfig(t *testing.T) {
+	m := NewKeyStoreManager(storage.NewMemory())
+	created, _, err := m.CreateKey("configured", kybertransit.DefaultKeyType, KeyConfig{Exportable: true})
+	require.NoError(t, err)
+	assert.Equal(t, 1, created.MinDecryptionVersion)
+	_, err = m.RotateKey("configured")
+	require.NoError(t, err)
+
+	key, err := m.UpdateKeyConfig("configured", func(c KeyConfig) KeyConfig {
+		c.MinDecryptionVersion = 2
+		return c
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, key.MinDecryptionVersion)
+	assert.True(t, key.Exportable)
+
+	_, err = m.UpdateKeyConfig("configured", func(c KeyConfig) KeyConfig {
+		c.Exportable = false
+		return c
+	})
+	assert.ErrorIs(t, err, ErrInvalidKeyConfig)
+	_, err = m.UpdateKeyConfig("configured", func(c KeyConfig) KeyConfig {
+		c.Derived = true
+		return c
+	})
+	assert.ErrorIs(t, err, ErrInvalidKeyConfig)
+	_, err = m.UpdateKeyConfig("missing", func(c KeyConfig) KeyConfig { return c })
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+
+	key, err = m.GetKey("configured")
+	require.NoError(t, err)
+	assert.Equal(t, 2, key.MinDecryptionVersion)
+	assert.True(t, key.Exportable)
+}
+
+func TestKeyStoreManager_RotateDueKeys(t *testing.T) {
+	dir := t.TempDir()
+	backend, err := storage.NewFile(dir)
+	require.NoError(t, err)
+	m := NewKeyStoreManager(backend)
+
+	_, _, err = m.CreateKey("daily", kybertransit.DefaultKeyType, KeyConfig{AutoRotatePeriod: 24 * time.Hour})
+	require.NoError(t, err)
+	_, _, err = m.CreateKey("manual", kybertransit.DefaultKeyType, KeyConfig{})
+	require.NoError(t, err)
+	_, _, err = m.CreateKey("too-often", kybertransit.DefaultKeyType, KeyConfig{AutoRotatePeriod: time.Minute})
+	assert.ErrorIs(t, err, ErrInvalidKeyConfig)
+
+	rotated, err := m.RotateDueKeys(time.Now())
+	require.NoError(t, err)
+	assert.Empty(t, rotated)
+
+	rotated, err = m.RotateDueKeys(time.Now().Add(25 * time.Hour))
+	require.NoError(t, err)
+	require.Len(t, rotated, 1)
+	assert.Equal(t, "daily", rotated[0].Name)
+	assert.Equal(t, 2, rotated[0].LatestVersion())
+
+	// The new version restarts the period, also after a restart.
+	reopened, err := storage.NewFile(dir)
+	require.NoError(t, err)
+	m = NewKeyStoreManager(reopened)
+	rotated, err = m.RotateDueKeys(time.Now().Add(23 * time.Hour))
+	require.NoError(t, err)
+	assert.Empty(t, rotated)
+	key, err := m.GetKey("daily")
+	require.NoError(t, err)
+	assert.Equal(t, 2, key.LatestVersion())
+	assert.Equal(t, 24*time.Hour, key.AutoRotatePeriod)
+	key, err = m.GetKey("manual")
+	require.NoError(t, err)
+	assert.Equal(t, 1, key.LatestVersion())
+}
+
+func TestKeyStoreManager_TrimKey(t *testing.T) {
+	dir := t.TempDir()
+	backend, err := storage.NewFile(dir)
+	require.NoError(t, err)
+	m := NewKeyStoreManager(backend)
+
+	created, _, err := m.CreateKey("trimmed", kybertransit.DefaultKeyType, KeyConfig{AutoRotatePeriod: time.Hour})
+	require.NoError(t, err)
+	for range 3 {
+		_, err = m.RotateKey("trimmed")
+		require.NoError(t, err)
+	}
+
+	_, err = m.TrimKey("trimmed", 2)
+	assert.ErrorIs(t, err, ErrInvalidKeyConfig, "cannot trim past min_decryption_version")
+	_, err = m.TrimKey("missing", 1)
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+
+	_, err = m.UpdateKeyConfig("trimmed", func(c KeyConfig) KeyConfig {
+		c.MinDecryptionVersion = 3
+		return c
+	})
+	require.NoError(t, err)
+	key, err := m.TrimKey("trimmed", 3)
+	require.NoError(t, err)
+	assert.Equal(t, 3, key.MinAvailableVersion())
+	assert.Len(t, key.Versions, 2)
+
+	// Trimmed versions stay gone after a restart and cannot be brought back.
+	reopened, err := storage.NewFile(dir)
+	require.NoError(t, err)
+	m = NewKeyStoreManager(reopened)
+	key, err = m.GetKey("trimmed")
+	require.NoError(t, err)
+	_, ok := key.Version(1)
+	assert.False(t, ok)
+	_, ok = key.Version(3)
+	assert.True(t, ok)
+	for _, kv := range key.Versions {
+		assert.NotEqual(t, created.Latest().KeyPair.PrivateKey, kv.KeyPair.PrivateKey)
+	}
+	_, err = m.TrimKey("trimmed", 2)
+	assert.ErrorIs(t, err, ErrInvalidKeyConfig)
+	_, err = m.UpdateKeyConfig("trimmed", func(c KeyConfig) KeyConfig {
+		c.MinDecryptionVersion = 2
+		return c
+	})
+	assert.ErrorIs(t, err, ErrInvalidKeyConfig)
+
+	// Rotation continues from the latest version, manually and automatically.
+	key, err = m.RotateKey("trimmed")
+	require.NoError(t, err)
+	assert.Equal(t, 5, key.LatestVersion())
+	rotated, err := m.RotateDueKeys(time.Now().Add(2 * time.Hour))
+	require.NoError(t, err)
+	require.Len(t, rotated, 1)
+	assert.Equal(t, 6, rotated[0].LatestVersion())
+	assert.Equal(t, 3, rotated[0].MinAvailableVersion())
+}
@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/dezween/ElevexaCodingChallenge2/internal/policy"
+	"github.com/dezween/ElevexaCodingChallenge2/internal/routes"
+	"github.com/gorilla/mux"
+)
+
+// routeCapabilities maps each authenticated route (by mux route name) to the
+// capability a token's policies must grant on the request path.
+// Routes missing from this map and from selfServiceRoutes are denied.
+var routeCapabilities = map[string]policy.Capability{
+	routes.RouteNameCreateKey:    policy.Create,
+	routes.RouteNameReadKey:      policy.Read,
+	routes.RouteNameDeleteKey:    policy.Delete,
+	routes.RouteNameListKeys:     policy.List,
+	routes.RouteNameRotateKey:    policy.Rotate,
+	routes.RouteNameKeyConfig:    policy.Update,
+	routes.RouteNameTrimKey:      policy.Update,
+	routes.RouteNameEncrypt:      policy.Encrypt,
+	routes.RouteNameDecrypt:      policy.Decrypt,
+	routes.RouteNameRewrap:       policy.Rewrap,
+	routes.RouteNameDataKey:      policy.Encrypt,
+	routes.RouteNameSign:         policy.Sign,
+	routes.RouteNameVerify:       policy.Verify,
+	routes.RouteNameTokenCreate:  policy.Create,
+	routes.RouteNameTokenRevoke:  policy.Delete,
+	routes.RouteNamePolicyWrite:  policy.Create,
+	routes.RouteNamePolicyRead:   policy.Read,
+	routes.RouteNamePolicyDelete: policy.Delete,
+	routes.RouteNamePolicyList:   policy.List,
+
+	// Private keys are additionally only exported from exportable keys.
+	routes.RouteNameExportKey:     policy.Read,
+	routes.RouteNameExportVersion: policy.Read,
+	// Backups are additionally only taken of keys with allow_plaintext_backup.
+	routes.RouteNameBackup:  policy.Read,
+	routes.RouteNameRestore: policy.Create,
+}
+
+// selfServiceRoutes operate only on the calling token and are open to every valid token.
+var selfServiceRoutes = map[string]bool{
+	routes.RouteNameTokenLookupSelf: true,
+	routes.RouteNameTokenRevokeSelf: true,
+}
+
+// permissionDenied writes the 403 body used for every authorization failure.
+func permissionDenied(w http.ResponseWriter) {
+	writeJSON(w, http.StatusForbidden, map[string]string{"error": "Permission denied"})
+}
+
+// Authorize is middleware that enforces the ACL policies of the request token.
+// It must run after RequireToken. Denials return 403 {"error": "Permission denied"}.
+func Authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		info, ok := requestToken(r)
+		route := mux.CurrentRoute(r)
+		if !ok || route == nil {
+			permissionDenied(w)
+			return
+		}
+		name := route.GetName()
+		if selfServiceRoutes[name] {
+			next.ServeHTTP(w, r)
+			return
+		}
+		capability, known := routeCapabilities[name]
+		if !known {
+			log.Printf("[ERROR] no capability registered for route %q", name)
+			permissionDenied(w)
+			return
+		}
+		allowed, err := policyStore.Allowed(info.Token.Policies, r.URL.Path, capability)
+		if err != nil {
+			writeError(w, fmt.Errorf("policy check failed: %w", err))
+			return
+		}
+		if !allowed {
+			permissionDenied(w)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// PolicyWriteHandler handles POST /sys/policy/{name}.
+// Creates or replaces the named policy from {"rules": [{"path": "...", "capabilities": [...]}]}.
+// Returns 204 on success, 400 on invalid policy.
+func PolicyWriteHandler(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+		return
+	}
+	var req struct {
+		Rules []policy.Rule `json:"rules"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid JSON"})
+		return
+	}
+	err = policyStore.Put(policy.Policy{Name: name, Rules: req.Rules})
+	if errors.Is(err, policy.ErrReadOnly) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Root policy cannot be modified"})
+		return
+	}
+	if errors.Is(err, policy.ErrInvalid) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid policy: " + strings.TrimPrefix(err.Error(), policy.ErrInvalid.Error()+": ")})
+		return
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PolicyReadHandler handles GET /sys/policy/{name}.
+// Returns 200 with the policy, 404 if it does not exist.
+func PolicyReadHandler(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	p, err := policyStore.Get(name)
+	if errors.Is(err, policy.ErrNotFound) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "Policy not found"})
+		return
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, p)
+}
+
+// PolicyDeleteHandler handles DELETE /sys/policy/{name}.
+// Returns 204 on success, 400 for the root policy, 404 if it does not exist.
+func PolicyDeleteHandler(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	err := policyStore.Delete(name)
+	if errors.Is(err, policy.ErrReadOnly) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Root policy cannot be modified"})
+		return
+	}
+	if errors.Is(err, policy.ErrNotFound) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "Policy not found"})
+		return
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PolicyListHandler handles GET /sys/policy.
+// Returns 200 with the names of all stored policies.
+func PolicyListHandler(w http.ResponseWriter, _ *http.Request) {
+	names, err := policyStore.List()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string][]string{"policies": names})
+}
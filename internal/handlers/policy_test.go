@@ -0,0 +1,154 @@
+package handlers_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/dezween/ElevexaCodingChallenge2/internal/routes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// issueToken creates a child token with the given policies using the root token.
+func issueToken(t *testing.T, r *testRouter, policies ...string) string {
+	t.Helper()
+	createURL, _ := r.Get(routes.RouteNameTokenCreate).URL()
+	code, resp := doJSON(t, r, "POST", createURL.String(), map[string]interface{}{"policies": policies})
+	require.Equal(t, http.StatusOK, code)
+	return resp["token"].(string)
+}
+
+func TestPolicyEnforcement(t *testing.T) {
+	r := newTestRouter(t)
+	for _, name := range []string{"payments-api", "hr-records"} {
+		keyURL, _ := r.Get(routes.RouteNameCreateKey).URL("name", name)
+		code, _ := doJSON(t, r, "POST", keyURL.String(), nil)
+		require.Equal(t, http.StatusCreated, code)
+	}
+	policyURL, _ := r.Get(routes.RouteNamePolicyWrite).URL("name", "payments-encrypt")
+	code, _ := doJSON(t, r, "POST", policyURL.String(), map[string]interface{}{
+		"rules": []map[string]interface{}{
+			{"path": "/transit/encrypt/payments-*", "capabilities": []string{"encrypt"}},
+		},
+	})
+	require.Equal(t, http.StatusNoContent, code)
+	token := issueToken(t, r, "payments-encrypt")
+
+	encURL, _ := r.Get(routes.RouteNameEncrypt).URL("name", "payments-api")
+	_, enc := doJSON(t, r, "POST", encURL.String(), map[string]string{"plaintext": b64("card")})
+	ciphertext := enc["ciphertext"]
+
+	tests := []struct {
+		name       string
+		method     string
+		route      string
+		keyName    string
+		body       interface{}
+		wantStatus int
+	}{
+		{"encrypt allowed key", "POST", routes.RouteNameEncrypt, "payments-api", map[string]string{"plaintext": b64("card")}, http.StatusOK},
+		{"encrypt other key", "POST", routes.RouteNameEncrypt, "hr-records", map[string]string{"plaintext": b64("salary")}, http.StatusForbidden},
+		{"decrypt not granted", "POST", routes.RouteNameDecrypt, "payments-api", map[string]interface{}{"ciphertext": ciphertext}, http.StatusForbidden},
+		{"rotate not granted", "POST", routes.RouteNameRotateKey, "payments-api", nil, http.StatusForbidden},
+		{"sign not granted", "POST", routes.RouteNameSign, "payments-api", map[string]string{"input": "YQ=="}, http.StatusForbidden},
+		{"create key not granted", "POST", routes.RouteNameCreateKey, "payments-new", nil, http.StatusForbidden},
+		{"policy read not granted", "GET", routes.RouteNamePolicyRead, "payments-encrypt", nil, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			url, _ := r.Get(tt.route).URL("name", tt.keyName)
+			code, resp := doJSONWithToken(t, r, token, tt.method, url.String(), tt.body)
+			assert.Equal(t, tt.wantStatus, code)
+			if tt.wantStatus == http.StatusForbidden {
+				assert.Equal(t, map[string]interface{}{"error": "Permission denied"}, resp)
+			}
+		})
+	}
+
+	// Self-service token routes stay open to every token.
+	lookupURL, _ := r.Get(routes.RouteNameTokenLookupSelf).URL()
+	code, resp := doJSONWithToken(t, r, token, "GET", lookupURL.String(), nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []interface{}{"payments-encrypt"}, resp["policies"])
+}
+
+func TestTokenCreate_PolicySubset(t *testing.T) {
+	r := newTestRouter(t)
+	for _, name := range []string{"a", "b"} {
+		url, _ := r.Get(routes.RouteNamePolicyWrite).URL("name", name)
+		code, _ := doJSON(t, r, "POST", url.String(), map[string]interface{}{
+			"rules": []map[string]interface{}{
+				{"path": "/auth/token/create", "capabilities": []string{"create"}},
+			},
+		})
+		require.Equal(t, http.StatusNoContent, code)
+	}
+	parent := issueToken(t, r, "a")
+	createURL, _ := r.Get(routes.RouteNameTokenCreate).URL()
+
+	tests := []struct {
+		name         string
+		body         interface{}
+		wantStatus   int
+		wantPolicies []interface{}
+	}{
+		{"inherit", nil, http.StatusOK, []interface{}{"a"}},
+		{"subset", map[string]interface{}{"policies": []string{"a"}}, http.StatusOK, []interface{}{"a"}},
+		{"escalate", map[string]interface{}{"policies": []string{"a", "b"}}, http.StatusForbidden, nil},
+		{"root", map[string]interface{}{"policies": []string{"root"}}, http.StatusForbidden, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, resp := doJSONWithToken(t, r, parent, "POST", createURL.String(), tt.body)
+			assert.Equal(t, tt.wantStatus, code)
+			if tt.wantPolicies != nil {
+				assert.Equal(t, tt.wantPolicies, resp["policies"])
+			}
+		})
+	}
+}
+
+func TestPolicyHandlers(t *testing.T) {
+	r := newTestRouter(t)
+	url := func(route, name string) string {
+		u, _ := r.Get(route).URL("name", name)
+		return u.String()
+	}
+	listURL, _ := r.Get(routes.RouteNamePolicyList).URL()
+	rules := map[string]interface{}{
+		"rules": []map[string]interface{}{
+			{"path": "/transit/*/payments-*", "capabilities": []string{"encrypt", "decrypt"}},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		url        string
+		body       interface{}
+		wantStatus int
+		wantError  string
+	}{
+		{"write", "POST", url(routes.RouteNamePolicyWrite, "payments"), rules, http.StatusNoContent, ""},
+		{"read", "GET", url(routes.RouteNamePolicyRead, "payments"), nil, http.StatusOK, ""},
+		{"invalid JSON", "POST", url(routes.RouteNamePolicyWrite, "payments"), "notjson", http.StatusBadRequest, "Invalid JSON"},
+		{"unknown capability", "POST", url(routes.RouteNamePolicyWrite, "bad"), map[string]interface{}{
+			"rules": []map[string]interface{}{{"path": "/x", "capabilities": []string{"sudo"}}},
+		}, http.StatusBadRequest, ""},
+		{"write root", "POST", url(routes.RouteNamePolicyWrite, "root"), rules, http.StatusBadRequest, "Root policy cannot be modified"},
+		{"delete root", "DELETE", url(routes.RouteNamePolicyDelete, "root"), nil, http.StatusBadRequest, "Root policy cannot be modified"},
+		{"list", "GET", listURL.String(), nil, http.StatusOK, ""},
+		{"delete", "DELETE", url(routes.RouteNamePolicyDelete, "payments"), nil, http.StatusNoContent, ""},
+		{"read deleted", "GET", url(routes.RouteNamePolicyRead, "payments"), nil, http.StatusNotFound, "Policy not found"},
+		{"delete missing", "DELETE", url(routes.RouteNamePolicyDelete, "payments"), nil, http.StatusNotFound, "Policy not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, resp := doJSON(t, r, tt.method, tt.url, tt.body)
+			assert.Equal(t, tt.wantStatus, code)
+			if tt.wantError != "" {
+				assert.Equal(t, tt.wantError, resp["error"])
+			}
+		})
+	}
+}
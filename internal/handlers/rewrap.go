@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// RewrapHandler handles POST /transit/rewrap/{name}.
+// Decrypts a ciphertext (token or legacy ciphertext+encdata pair) with the key version
+// recorded in it and re-encrypts the plaintext with the latest key version. The
+// plaintext never leaves the server. With "batch_input" every item is rewrapped and
+// reported in "batch_results" (see batch.go).
+// Returns 200 and ciphertext+key_version on success, 404 if key not found, 400/500 on error.
+func RewrapHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	name := vars["name"]
+	key, err := keyStoreManager.GetKey(name)
+	if errors.Is(err, ErrKeyNotFound) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "Key not found"})
+		return
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if !key.Type.SupportsEncryption() {
+		writeError(w, unsupportedOperation(key, "encryption"))
+		return
+	}
+	body, err := readBatchBody(w, r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var req struct {
+		decryptItem
+		BatchInput []decryptItem `json:"batch_input"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid JSON"})
+		return
+	}
+	setAuditKeyVersion(r, key.LatestVersion())
+	if req.BatchInput != nil {
+		if err := checkBatchInput(len(req.BatchInput), req.Ciphertext != ""); err != nil {
+			writeError(w, err)
+			return
+		}
+		results := make([]map[string]interface{}, len(req.BatchInput))
+		runBatch(len(req.BatchInput), func(i int) {
+			results[i] = batchResult(rewrapItem(key, req.BatchInput[i]))
+		})
+		writeJSON(w, http.StatusOK, map[string]interface{}{"batch_results": results})
+		return
+	}
+	resp, err := rewrapItem(key, req.decryptItem)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// rewrapItem decrypts one item and encrypts its plaintext with the latest key version,
+// bound to the same associated data and context.
+func rewrapItem(key Key, item decryptItem) (map[string]interface{}, error) {
+	plaintext, _, err := decryptItemWithKey(key, item)
+	if err != nil {
+		return nil, err
+	}
+	associatedData, err := decodeAssociatedData(item.AssociatedData)
+	if err != nil {
+		return nil, err
+	}
+	context, err := keyContext(key, item.Context)
+	if err != nil {
+		return nil, err
+	}
+	return sealPlaintext(key, key.Latest(), plaintext, associatedData, context)
+}
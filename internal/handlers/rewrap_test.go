@@ -0,0 +1,75 @@
+package handlers_test
+
+import (
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/dezween/ElevexaCodingChallenge2/internal/kybertransit"
+	"github.com/dezween/ElevexaCodingChallenge2/internal/routes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRewrapHandler(t *testing.T) {
+	r := newTestRouter(t)
+	keyURL, _ := r.Get(routes.RouteNameCreateKey).URL("name", testKey1)
+	rotateURL, _ := r.Get(routes.RouteNameRotateKey).URL("name", testKey1)
+	encURL, _ := r.Get(routes.RouteNameEncrypt).URL("name", testKey1)
+	decURL, _ := r.Get(routes.RouteNameDecrypt).URL("name", testKey1)
+	rewrapURL, _ := r.Get(routes.RouteNameRewrap).URL("name", testKey1)
+
+	_, created := doJSON(t, r, "POST", keyURL.String(), nil)
+	pubKey, _ := base64.StdEncoding.DecodeString(created["public_key"].(string))
+	_, v1 := doJSON(t, r, "POST", encURL.String(), map[string]string{"plaintext": b64("stored secret")})
+	legacyCT, legacyEnc, err := kybertransit.Encrypt(kybertransit.DefaultKeyType, pubKey, []byte("legacy secret"), nil)
+	require.NoError(t, err)
+	code, _ := doJSON(t, r, "POST", rotateURL.String(), nil)
+	require.Equal(t, http.StatusOK, code)
+
+	tests := []struct {
+		name          string
+		body          map[string]string
+		wantPlaintext string
+	}{
+		{"envelope", map[string]string{"ciphertext": v1["ciphertext"].(string)}, b64("stored secret")},
+		{"legacy two-field form", map[string]string{"ciphertext": legacyCT, "encdata": legacyEnc}, b64("legacy secret")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, resp := doJSON(t, r, "POST", rewrapURL.String(), tt.body)
+			require.Equal(t, http.StatusOK, code, resp)
+			assert.Equal(t, float64(2), resp["key_version"])
+			assert.True(t, strings.HasPrefix(resp["ciphertext"].(string), "kyber:v2:"))
+			assert.NotContains(t, resp, "plaintext")
+
+			code, dec := doJSON(t, r, "POST", decURL.String(), map[string]interface{}{"ciphertext": resp["ciphertext"]})
+			require.Equal(t, http.StatusOK, code, dec)
+			assert.Equal(t, tt.wantPlaintext, dec["plaintext"])
+		})
+	}
+
+	code, resp := doJSON(t, r, "POST", rewrapURL.String(), map[string]interface{}{
+		"batch_input": []map[string]interface{}{
+			{"ciphertext": v1["ciphertext"]},
+			{"ciphertext": tamperToken(v1["ciphertext"].(string))},
+			{},
+		},
+	})
+	require.Equal(t, http.StatusOK, code, resp)
+	results := resp["batch_results"].([]interface{})
+	require.Len(t, results, 3)
+	assert.True(t, strings.HasPrefix(results[0].(map[string]interface{})["ciphertext"].(string), "kyber:v2:"))
+	assert.Equal(t, "Decryption failed: ciphertext was modified or associated_data does not match", results[1].(map[string]interface{})["error"])
+	assert.Equal(t, "Missing ciphertext", results[2].(map[string]interface{})["error"])
+
+	code, resp = doJSON(t, r, "POST", rewrapURL.String(), map[string]string{"ciphertext": "kyber:v1:bad"})
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "Invalid ciphertext format", resp["error"])
+
+	unknownURL, _ := r.Get(routes.RouteNameRewrap).URL("name", unknownKey)
+	code, resp = doJSON(t, r, "POST", unknownURL.String(), map[string]string{"ciphertext": "kyber:v1:bad"})
+	assert.Equal(t, http.StatusNotFound, code)
+	assert.Equal(t, "Key not found", resp["error"])
+}
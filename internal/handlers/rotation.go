@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dezween/ElevexaCodingChallenge2/internal/audit"
+	"github.com/dezween/ElevexaCodingChallenge2/internal/barrier"
+	"github.com/dezween/ElevexaCodingChallenge2/internal/routes"
+)
+
+// autoRotateRoute is the audit route name of rotations done by the scheduler.
+const autoRotateRoute = "autoRotate"
+
+// StartAutoRotation starts a background goroutine that checks every interval for
+// keys whose auto_rotate_period has elapsed and rotates them. Checks are skipped
+// while the barrier is sealed. The returned function stops the goroutine and waits
+// for a running check to finish. Must be called after UseStorage and UseAudit.
+func StartAutoRotation(interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case now := <-ticker.C:
+				RotateDueKeys(now)
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			wg.Wait()
+		})
+	}
+}
+
+// RotateDueKeys rotates every key that is due for automatic rotation at now and
+// records each rotation in the server log and the audit log. Returns the number of
+// keys rotated; nothing is rotated while the barrier is sealed.
+func RotateDueKeys(now time.Time) int {
+	rotated, err := keyStoreManager.RotateDueKeys(now)
+	if errors.Is(err, barrier.ErrSealed) {
+		return 0
+	}
+	if err != nil {
+		log.Printf("[ERROR] automatic key rotation failed: %v", err)
+	}
+	for _, key := range rotated {
+		log.Printf("Automatically rotated key %q to version %d", key.Name, key.LatestVersion())
+		if auditLog == nil {
+			continue
+		}
+		err := auditLog.Log(audit.Entry{
+			Time: time.Now().UTC(),
+			Request: audit.Request{
+				ID:         newRequestID(),
+				Route:      autoRotateRoute,
+				Path:       strings.Replace(routes.RouteRotateKey, "{name}", key.Name, 1),
+				KeyName:    key.Name,
+				KeyVersion: key.LatestVersion(),
+			},
+			Response: audit.Response{Status: http.StatusOK, Result: "success"},
+		})
+		if err != nil {
+			log.Printf("[ERROR] failed to audit automatic rotation of key %q: %v", key.Name, err)
+		}
+	}
+	return len(rotated)
+}
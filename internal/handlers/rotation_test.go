@@ -0,0 +1,80 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dezween/ElevexaCodingChallenge2/internal/audit"
+	"github.com/dezween/ElevexaCodingChallenge2/internal/handlers"
+	"github.com/dezween/ElevexaCodingChallenge2/internal/routes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRotateDueKeys(t *testing.T) {
+	r := newTestRouter(t)
+	var buf bytes.Buffer
+	handlers.UseAudit(audit.NewWriter(&buf))
+	t.Cleanup(func() { handlers.UseAudit() })
+
+	key1URL, _ := r.Get(routes.RouteNameCreateKey).URL("name", testKey1)
+	key2URL, _ := r.Get(routes.RouteNameCreateKey).URL("name", testKey2)
+	key3URL, _ := r.Get(routes.RouteNameCreateKey).URL("name", testKey3)
+	code, resp := doJSON(t, r, "POST", key1URL.String(), map[string]interface{}{"auto_rotate_period": "24h"})
+	require.Equal(t, http.StatusCreated, code, resp)
+	code, _ = doJSON(t, r, "POST", key2URL.String(), nil)
+	require.Equal(t, http.StatusCreated, code)
+	code, resp = doJSON(t, r, "POST", key3URL.String(), map[string]interface{}{"auto_rotate_period": "10m"})
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "Invalid key config: auto_rotate_period must be 0 (disabled) or at least 1h0m0s", resp["error"])
+
+	assert.Equal(t, 0, handlers.RotateDueKeys(time.Now()))
+	assert.Equal(t, 1, handlers.RotateDueKeys(time.Now().Add(25*time.Hour)))
+
+	tests := []struct {
+		name        string
+		url         string
+		wantVersion float64
+		wantPeriod  string
+	}{
+		{"auto rotated", key1URL.String(), 2, "24h0m0s"},
+		{"auto rotation disabled", key2URL.String(), 1, "0s"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, resp := doJSON(t, r, "GET", tt.url, nil)
+			require.Equal(t, http.StatusOK, code)
+			assert.Equal(t, tt.wantVersion, resp["latest_version"])
+			assert.Equal(t, tt.wantPeriod, resp["auto_rotate_period"])
+		})
+	}
+
+	var rotation *audit.Entry
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var e audit.Entry
+		require.NoError(t, json.Unmarshal([]byte(line), &e))
+		if e.Request.Route == "autoRotate" {
+			rotation = &e
+		}
+	}
+	require.NotNil(t, rotation, "automatic rotation must be audited")
+	assert.Equal(t, testKey1, rotation.Request.KeyName)
+	assert.Equal(t, 2, rotation.Request.KeyVersion)
+	assert.Equal(t, "/transit/keys/"+testKey1+"/rotate", rotation.Request.Path)
+	assert.Equal(t, "success", rotation.Response.Result)
+
+	handlers.ResetKeyStore()
+	assert.Equal(t, 0, handlers.RotateDueKeys(time.Now().Add(50*time.Hour)), "sealed barrier must be skipped")
+}
+
+func TestStartAutoRotation_Stop(t *testing.T) {
+	handlers.ResetKeyStore()
+	stop := handlers.StartAutoRotation(time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	stop()
+	stop()
+}
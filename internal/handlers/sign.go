@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/dezween/ElevexaCodingChallenge2/internal/kybertransit"
+	"github.com/gorilla/mux"
+)
+
+// loadSigningKey looks up the named key for a sign or verify request and decodes the
+// JSON request body into v. Writes the error response and returns false if the key
+// is unknown, cannot sign, or the body is invalid.
+func loadSigningKey(w http.ResponseWriter, r *http.Request, v interface{}) (Key, bool) {
+	name := mux.Vars(r)["name"]
+	key, err := keyStoreManager.GetKey(name)
+	if errors.Is(err, ErrKeyNotFound) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "Key not found"})
+		return Key{}, false
+	}
+	if err != nil {
+		writeError(w, err)
+		return Key{}, false
+	}
+	if !key.Type.SupportsSigning() {
+		writeError(w, unsupportedOperation(key, "signing"))
+		return Key{}, false
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+		return Key{}, false
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid JSON"})
+		return Key{}, false
+	}
+	return key, true
+}
+
+// decodeInput decodes the base64 "input" field of a sign or verify request.
+func decodeInput(input string) ([]byte, error) {
+	if input == "" {
+		return nil, badRequest("Missing input")
+	}
+	data, err := base64.StdEncoding.DecodeString(input)
+	if err != nil {
+		return nil, badRequest("Invalid input: must be base64")
+	}
+	return data, nil
+}
+
+// SignHandler handles POST /transit/sign/{name}.
+// Signs the base64 "input" with the latest version of an ML-DSA key and returns a
+// "sig:v<version>:<base64>" signature that records the key version.
+// Returns 200 on success, 400 on invalid input or non-signing key, 404 if key not found.
+func SignHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Input string `json:"input"`
+	}
+	key, ok := loadSigningKey(w, r, &req)
+	if !ok {
+		return
+	}
+	latest := key.Latest()
+	setAuditKeyVersion(r, latest.Version)
+	input, err := decodeInput(req.Input)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	sig, err := kybertransit.Sign(key.Type, latest.KeyPair.PrivateKey, latest.Version, input)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"signature":   sig,
+		"key_version": latest.Version,
+	})
+}
+
+// VerifyHandler handles POST /transit/verify/{name}.
+// Checks a "sig:v<version>:<base64>" signature of the base64 "input" with the key
+// version recorded in the signature, which must not be below min_decryption_version.
+// Returns 200 with {"valid": bool}, 400 on malformed input or signature, 404 if key not found.
+func VerifyHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Input     string `json:"input"`
+		Signature string `json:"signature"`
+	}
+	key, ok := loadSigningKey(w, r, &req)
+	if !ok {
+		return
+	}
+	input, err := decodeInput(req.Input)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if req.Signature == "" {
+		writeError(w, badRequest("Missing signature"))
+		return
+	}
+	version, sig, err := kybertransit.ParseSignature(req.Signature)
+	if err != nil {
+		log.Printf("[ERROR] invalid signature: %v", err)
+		writeError(w, badRequest("Invalid signature format"))
+		return
+	}
+	setAuditKeyVersion(r, version)
+	if version < key.MinDecryptionVersion {
+		writeError(w, badRequest(fmt.Sprintf("Key version %d is below min_decryption_version %d", version, key.MinDecryptionVersion)))
+		return
+	}
+	kv, found := key.Version(version)
+	if !found {
+		writeError(w, badRequest("Key version not found"))
+		return
+	}
+	valid, err := kybertransit.Verify(key.Type, kv.KeyPair.PublicKey, input, sig)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]bool{"valid": valid})
+}
@@ -0,0 +1,103 @@
+package handlers_test
+
+import (
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/dezween/ElevexaCodingChallenge2/internal/routes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignVerifyHandlers(t *testing.T) {
+	r := newTestRouter(t)
+	keyURL, _ := r.Get(routes.RouteNameCreateKey).URL("name", testKey1)
+	rotateURL, _ := r.Get(routes.RouteNameRotateKey).URL("name", testKey1)
+	signURL, _ := r.Get(routes.RouteNameSign).URL("name", testKey1)
+	verifyURL, _ := r.Get(routes.RouteNameVerify).URL("name", testKey1)
+
+	code, resp := doJSON(t, r, "POST", keyURL.String(), map[string]string{"type": "ml-dsa-65"})
+	require.Equal(t, http.StatusCreated, code, resp)
+	assert.Equal(t, "ml-dsa-65", resp["type"])
+
+	input := base64.StdEncoding.EncodeToString([]byte("release-1.0.tar.gz"))
+	code, v1 := doJSON(t, r, "POST", signURL.String(), map[string]string{"input": input})
+	require.Equal(t, http.StatusOK, code, v1)
+	assert.True(t, strings.HasPrefix(v1["signature"].(string), "sig:v1:"))
+
+	code, _ = doJSON(t, r, "POST", rotateURL.String(), nil)
+	require.Equal(t, http.StatusOK, code)
+	code, v2 := doJSON(t, r, "POST", signURL.String(), map[string]string{"input": input})
+	require.Equal(t, http.StatusOK, code, v2)
+	assert.Equal(t, float64(2), v2["key_version"])
+	assert.True(t, strings.HasPrefix(v2["signature"].(string), "sig:v2:"))
+
+	other := base64.StdEncoding.EncodeToString([]byte("release-1.1.tar.gz"))
+	tests := []struct {
+		name       string
+		body       map[string]interface{}
+		wantStatus int
+		wantField  string
+		wantValue  interface{}
+	}{
+		{"version 1", map[string]interface{}{"input": input, "signature": v1["signature"]}, http.StatusOK, "valid", true},
+		{"version 2", map[string]interface{}{"input": input, "signature": v2["signature"]}, http.StatusOK, "valid", true},
+		{"other input", map[string]interface{}{"input": other, "signature": v2["signature"]}, http.StatusOK, "valid", false},
+		{"version swapped", map[string]interface{}{"input": input, "signature": strings.Replace(v2["signature"].(string), "sig:v2:", "sig:v1:", 1)}, http.StatusOK, "valid", false},
+		{"unknown version", map[string]interface{}{"input": input, "signature": strings.Replace(v2["signature"].(string), "sig:v2:", "sig:v9:", 1)}, http.StatusBadRequest, "error", "Key version not found"},
+		{"missing signature", map[string]interface{}{"input": input}, http.StatusBadRequest, "error", "Missing signature"},
+		{"malformed signature", map[string]interface{}{"input": input, "signature": "abc"}, http.StatusBadRequest, "error", "Invalid signature format"},
+		{"ciphertext token", map[string]interface{}{"input": input, "signature": "kyber:v2:" + strings.TrimPrefix(v2["signature"].(string), "sig:v2:")}, http.StatusBadRequest, "error", "Invalid signature format"},
+		{"missing input", map[string]interface{}{"signature": v2["signature"]}, http.StatusBadRequest, "error", "Missing input"},
+		{"input not base64", map[string]interface{}{"input": "!!!", "signature": v2["signature"]}, http.StatusBadRequest, "error", "Invalid input: must be base64"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, resp := doJSON(t, r, "POST", verifyURL.String(), tt.body)
+			assert.Equal(t, tt.wantStatus, code)
+			assert.Equal(t, tt.wantValue, resp[tt.wantField])
+		})
+	}
+
+	configURL, _ := r.Get(routes.RouteNameKeyConfig).URL("name", testKey1)
+	code, _ = doJSON(t, r, "POST", configURL.String(), map[string]int{"min_decryption_version": 2})
+	require.Equal(t, http.StatusOK, code)
+	code, resp = doJSON(t, r, "POST", verifyURL.String(), map[string]interface{}{"input": input, "signature": v1["signature"]})
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "Key version 1 is below min_decryption_version 2", resp["error"])
+}
+
+func TestSignVerifyHandlers_KeyTypeMismatch(t *testing.T) {
+	r := newTestRouter(t)
+	kemURL, _ := r.Get(routes.RouteNameCreateKey).URL("name", testKey1)
+	signingURL, _ := r.Get(routes.RouteNameCreateKey).URL("name", testKey2)
+	doJSON(t, r, "POST", kemURL.String(), nil)
+	doJSON(t, r, "POST", signingURL.String(), map[string]string{"type": "ml-dsa-44"})
+
+	signURL, _ := r.Get(routes.RouteNameSign).URL("name", testKey1)
+	encURL, _ := r.Get(routes.RouteNameEncrypt).URL("name", testKey2)
+	decURL, _ := r.Get(routes.RouteNameDecrypt).URL("name", testKey2)
+	unknownURL, _ := r.Get(routes.RouteNameSign).URL("name", unknownKey)
+
+	tests := []struct {
+		name       string
+		url        string
+		body       map[string]string
+		wantStatus int
+		wantError  string
+	}{
+		{"sign with KEM key", signURL.String(), map[string]string{"input": "YQ=="}, http.StatusBadRequest, "Key type kyber1024 does not support signing"},
+		{"encrypt with signing key", encURL.String(), map[string]string{"plaintext": b64("a")}, http.StatusBadRequest, "Key type ml-dsa-44 does not support encryption"},
+		{"decrypt with signing key", decURL.String(), map[string]string{"ciphertext": "kyber:v1:YQ=="}, http.StatusBadRequest, "Key type ml-dsa-44 does not support decryption"},
+		{"unknown key", unknownURL.String(), map[string]string{"input": "YQ=="}, http.StatusNotFound, "Key not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, resp := doJSON(t, r, "POST", tt.url, tt.body)
+			assert.Equal(t, tt.wantStatus, code)
+			assert.Equal(t, tt.wantError, resp["error"])
+		})
+	}
+}
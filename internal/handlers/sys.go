@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/dezween/ElevexaCodingChallenge2/internal/barrier"
+)
+
+// SealStatusHandler handles GET /sys/seal-status.
+// Reports whether the barrier is initialized and sealed, and the unseal progress. Always available.
+func SealStatusHandler(w http.ResponseWriter, _ *http.Request) {
+	status, err := keyBarrier.Status()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+// InitHandler handles POST /sys/init.
+// Generates the master key and splits it into secret_shares unseal keys, any
+// secret_threshold of which unseal the server, and issues the initial root token.
+// The keys and root token are returned once; only the token's hash is stored.
+// Returns 200 with the base64 keys and root_token, 400 on invalid parameters or if already initialized.
+func InitHandler(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+		return
+	}
+	var req barrier.SealConfig
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid JSON"})
+		return
+	}
+	var rootToken string
+	shares, err := keyBarrier.Init(req, func() error {
+		id, _, err := tokenStore.CreateRoot()
+		rootToken = id
+		return err
+	})
+	if errors.Is(err, barrier.ErrAlreadyInitialized) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Server is already initialized"})
+		return
+	}
+	if errors.Is(err, barrier.ErrInvalidSealConfig) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid seal configuration: " + strings.TrimPrefix(err.Error(), barrier.ErrInvalidSealConfig.Error()+": ")})
+		return
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	keys := make([]string, len(shares))
+	for i, share := range shares {
+		keys[i] = base64.StdEncoding.EncodeToString(share)
+	}
+	log.Printf("Server initialized with %d unseal key shares (threshold %d)", req.SecretShares, req.SecretThreshold)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys":       keys,
+		"root_token": rootToken,
+	})
+}
+
+// UnsealHandler handles POST /sys/unseal.
+// Accepts one base64-encoded unseal key share per call; the server unseals once
+// the threshold is reached. {"reset": true} discards the shares submitted so far.
+// Returns 200 with the seal status, 400 on malformed input or uninitialized server,
+// 403 if the combined shares do not form the master key.
+func UnsealHandler(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+		return
+	}
+	var req struct {
+		Key   string `json:"key"`
+		Reset bool   `json:"reset"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid JSON"})
+		return
+	}
+	if req.Reset {
+		keyBarrier.ResetUnsealProgress()
+		SealStatusHandler(w, r)
+		return
+	}
+	share, err := base64.StdEncoding.DecodeString(req.Key)
+	if err != nil || len(share) == 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Key must be base64-encoded"})
+		return
+	}
+	status, err := keyBarrier.SubmitUnsealKey(share)
+	if errors.Is(err, barrier.ErrNotInitialized) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Server is not initialized"})
+		return
+	}
+	if errors.Is(err, barrier.ErrInvalidKey) {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "Invalid unseal key"})
+		return
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if !status.Sealed {
+		log.Println("Server unsealed")
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+// RequireUnsealed is middleware that rejects requests with 503 while the barrier is sealed.
+func RequireUnsealed(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if keyBarrier.Sealed() {
+			writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "Server is sealed"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
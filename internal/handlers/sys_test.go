@@ -0,0 +1,107 @@
+package handlers_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/dezween/ElevexaCodingChallenge2/internal/handlers"
+	"github.com/dezween/ElevexaCodingChallenge2/internal/routes"
+	"github.com/dezween/ElevexaCodingChallenge2/internal/server"
+	"github.com/dezween/ElevexaCodingChallenge2/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInitHandler_TableDriven(t *testing.T) {
+	handlers.ResetKeyStore()
+	r := server.NewRouter()
+	initURL, _ := r.Get(routes.RouteNameInit).URL()
+
+	tests := []struct {
+		name       string
+		body       interface{}
+		wantStatus int
+		wantError  string
+	}{
+		{"invalid JSON", "notjson", http.StatusBadRequest, "Invalid JSON"},
+		{"threshold above shares", map[string]int{"secret_shares": 2, "secret_threshold": 3}, http.StatusBadRequest, "Invalid seal configuration: secret_threshold must be between 1 and secret_shares"},
+		{"success", map[string]int{"secret_shares": 5, "secret_threshold": 3}, http.StatusOK, ""},
+		{"already initialized", map[string]int{"secret_shares": 5, "secret_threshold": 3}, http.StatusBadRequest, "Server is already initialized"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, resp := doJSON(t, r, "POST", initURL.String(), tt.body)
+			assert.Equal(t, tt.wantStatus, code)
+			if tt.wantError != "" {
+				assert.Equal(t, tt.wantError, resp["error"])
+			} else {
+				assert.Len(t, resp["keys"], 5)
+			}
+		})
+	}
+}
+
+func TestUnsealHandler_SharesIncrementally(t *testing.T) {
+	backend := storage.NewMemory()
+	handlers.UseStorage(backend)
+	r := server.NewRouter()
+	initURL, _ := r.Get(routes.RouteNameInit).URL()
+	unsealURL, _ := r.Get(routes.RouteNameUnseal).URL()
+	statusURL, _ := r.Get(routes.RouteNameSealStatus).URL()
+	encURL, _ := r.Get(routes.RouteNameEncrypt).URL("name", testKey1)
+
+	code, resp := doJSON(t, r, "POST", unsealURL.String(), map[string]string{"key": "AAAA"})
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "Server is not initialized", resp["error"])
+
+	code, resp = doJSON(t, r, "POST", initURL.String(), map[string]int{"secret_shares": 3, "secret_threshold": 2})
+	require.Equal(t, http.StatusOK, code)
+	keys := resp["keys"].([]interface{})
+
+	code, resp = doJSON(t, r, "GET", statusURL.String(), nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, map[string]interface{}{"initialized": true, "sealed": true, "t": float64(2), "n": float64(3), "progress": float64(0)}, resp)
+	code, resp = doJSON(t, r, "POST", encURL.String(), map[string]string{"plaintext": b64("data")})
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "Server is sealed", resp["error"])
+
+	tests := []struct {
+		name         string
+		body         interface{}
+		wantStatus   int
+		wantError    string
+		wantSealed   bool
+		wantProgress float64
+	}{
+		{"invalid JSON", "notjson", http.StatusBadRequest, "Invalid JSON", true, 0},
+		{"not base64", map[string]string{"key": "!!!"}, http.StatusBadRequest, "Key must be base64-encoded", true, 0},
+		{"wrong length", map[string]string{"key": "AAAA"}, http.StatusForbidden, "Invalid unseal key", true, 0},
+		{"first share", map[string]interface{}{"key": keys[2]}, http.StatusOK, "", true, 1},
+		{"reset progress", map[string]bool{"reset": true}, http.StatusOK, "", true, 0},
+		{"first share again", map[string]interface{}{"key": keys[0]}, http.StatusOK, "", true, 1},
+		{"threshold reached", map[string]interface{}{"key": keys[2]}, http.StatusOK, "", false, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, resp := doJSON(t, r, "POST", unsealURL.String(), tt.body)
+			assert.Equal(t, tt.wantStatus, code)
+			if tt.wantError != "" {
+				assert.Equal(t, tt.wantError, resp["error"])
+				return
+			}
+			assert.Equal(t, tt.wantSealed, resp["sealed"])
+			assert.Equal(t, tt.wantProgress, resp["progress"])
+		})
+	}
+
+	// Simulate a restart: same backend, new sealed barrier; a different pair of shares unseals.
+	handlers.UseStorage(backend)
+	r = server.NewRouter()
+	for _, key := range []interface{}{keys[1], keys[0]} {
+		code, resp = doJSON(t, r, "POST", unsealURL.String(), map[string]interface{}{"key": key})
+		require.Equal(t, http.StatusOK, code)
+	}
+	assert.Equal(t, false, resp["sealed"])
+}
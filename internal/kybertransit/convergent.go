@@ -0,0 +1,69 @@
+package kybertransit
+
+import (
+	"crypto/hkdf"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+)
+
+// convergentKeyInfoPrefix starts the HKDF info of the secret that convergent encryption
+// derives encapsulation seeds and nonces from. It is followed by the algorithm name.
+const convergentKeyInfoPrefix = "kybertransit/v1 convergent "
+
+// EncryptEnvelopeConvergent encrypts like EncryptEnvelope, but deterministically: the
+// same key pair, key version, plaintext, associated data and context always yield the
+// same token, so equal plaintexts can be found by comparing ciphertexts.
+//
+// The construction is SIV-style: an HMAC-SHA256 over the key version, context, associated
+// data and plaintext, keyed with a secret derived from the private key, seeds the KEM
+// encapsulation and the AES-GCM nonce. Only the holder of the private key can produce
+// (and so test guesses against) convergent ciphertexts, and a nonce is only ever reused
+// for the identical message. Tokens reveal plaintext equality by design.
+// DecryptEnvelope decrypts them like any other envelope.
+func EncryptEnvelopeConvergent(keyType KeyType, keyPair KeyPair, keyVersion int, plaintext []byte, associatedData []byte, context []byte) (string, error) {
+	info, err := keyType.info()
+	if err != nil {
+		return "", err
+	}
+	pk, err := info.scheme.UnmarshalBinaryPublicKey(keyPair.PublicKey)
+	if err != nil || pk == nil {
+		return "", fmt.Errorf("kyber: failed to unmarshal public key: %w", err)
+	}
+	secret, err := hkdf.Key(sha256.New, keyPair.PrivateKey, nil, convergentKeyInfoPrefix+info.algorithm.String(), sha256.Size)
+	if err != nil {
+		return "", fmt.Errorf("kyber: failed to derive convergent key: %w", err)
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(binary.BigEndian.AppendUint64(nil, uint64(keyVersion)))
+	for _, part := range [][]byte{context, associatedData, plaintext} {
+		mac.Write(binary.BigEndian.AppendUint64(nil, uint64(len(part))))
+		mac.Write(part)
+	}
+	digest := mac.Sum(nil)
+	seed, err := hkdf.Expand(sha256.New, digest, "encapsulation seed", info.scheme.EncapsulationSeedSize())
+	if err != nil {
+		return "", fmt.Errorf("kyber: failed to derive encapsulation seed: %w", err)
+	}
+	nonce, err := hkdf.Expand(sha256.New, digest, "nonce", nonceSize)
+	if err != nil {
+		return "", fmt.Errorf("kyber: failed to derive nonce: %w", err)
+	}
+	kemCT, ss, err := info.scheme.EncapsulateDeterministically(pk, seed)
+	if err != nil {
+		return "", fmt.Errorf("kyber: encapsulation failed: %w", err)
+	}
+	aead, err := newDEM(info.algorithm, ss, context)
+	if err != nil {
+		return "", err
+	}
+	env := Envelope{
+		Algorithm:     info.algorithm,
+		KeyVersion:    keyVersion,
+		KEMCiphertext: kemCT,
+		Nonce:         nonce,
+		Data:          aead.Seal(nil, nonce, plaintext, associatedData),
+	}
+	return env.String(), nil
+}
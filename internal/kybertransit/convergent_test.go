@@ -0,0 +1,66 @@
+package kybertransit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncryptEnvelopeConvergent_TableDriven(t *testing.T) {
+	for _, keyType := range []KeyType{KeyTypeKyber1024, KeyTypeMLKEM768, KeyTypeX25519MLKEM768} {
+		t.Run(string(keyType), func(t *testing.T) {
+			kp, err := GenerateKeyPair(keyType)
+			require.NoError(t, err)
+			other, err := GenerateKeyPair(keyType)
+			require.NoError(t, err)
+			encrypt := func(kp KeyPair, version int, plaintext, associatedData, context string) string {
+				token, err := EncryptEnvelopeConvergent(keyType, kp, version, []byte(plaintext), []byte(associatedData), []byte(context))
+				require.NoError(t, err)
+				return token
+			}
+			base := encrypt(kp, 1, "alice@example.com", "", "users")
+			baseEnv, err := ParseEnvelope(base)
+			require.NoError(t, err)
+
+			tests := []struct {
+				name      string
+				token     string
+				wantEqual bool
+			}{
+				{"same inputs", encrypt(kp, 1, "alice@example.com", "", "users"), true},
+				{"other plaintext", encrypt(kp, 1, "bob@example.com", "", "users"), false},
+				{"other context", encrypt(kp, 1, "alice@example.com", "", "admins"), false},
+				{"other associated data", encrypt(kp, 1, "alice@example.com", "row-1", "users"), false},
+				{"other key version", encrypt(kp, 2, "alice@example.com", "", "users"), false},
+				{"other key pair", encrypt(other, 1, "alice@example.com", "", "users"), false},
+			}
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					// Compare the sealed parts only: the key version is also visible in the
+					// header, which must not be what tells the tokens apart.
+					env, err := ParseEnvelope(tt.token)
+					require.NoError(t, err)
+					if tt.wantEqual {
+						assert.Equal(t, base, tt.token)
+					} else {
+						assert.NotEqual(t, baseEnv.KEMCiphertext, env.KEMCiphertext)
+						assert.NotEqual(t, baseEnv.Nonce, env.Nonce)
+						assert.NotEqual(t, baseEnv.Data, env.Data)
+					}
+				})
+			}
+
+			pt, err := DecryptEnvelope(keyType, kp.PrivateKey, baseEnv, []byte(""), []byte("users"))
+			require.NoError(t, err)
+			assert.Equal(t, []byte("alice@example.com"), pt)
+			_, err = DecryptEnvelope(keyType, kp.PrivateKey, baseEnv, nil, []byte("admins"))
+			assert.ErrorIs(t, err, ErrAuthentication)
+		})
+	}
+
+	signKey, err := GenerateKeyPair(KeyTypeMLDSA44)
+	require.NoError(t, err)
+	_, err = EncryptEnvelopeConvergent(KeyTypeMLDSA44, signKey, 1, []byte("data"), nil, []byte("ctx"))
+	assert.ErrorContains(t, err, "does not support encryption")
+}
@@ -0,0 +1,103 @@
+package kybertransit
+
+import (
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+)
+
+// ErrNoStandardEncoding is returned when encoding a key of a type that has no
+// registered algorithm identifier (round-3 Kyber and X-Wing).
+var ErrNoStandardEncoding = errors.New("kyber: key type has no standard key encoding")
+
+// keyTypeOIDs maps the key types with a standard encoding to their NIST algorithm
+// identifiers (id-alg-ml-kem-* and id-ml-dsa-*).
+var keyTypeOIDs = map[KeyType]asn1.ObjectIdentifier{
+	KeyTypeMLKEM512:  {2, 16, 840, 1, 101, 3, 4, 4, 1},
+	KeyTypeMLKEM768:  {2, 16, 840, 1, 101, 3, 4, 4, 2},
+	KeyTypeMLKEM1024: {2, 16, 840, 1, 101, 3, 4, 4, 3},
+	KeyTypeMLDSA44:   {2, 16, 840, 1, 101, 3, 4, 3, 17},
+	KeyTypeMLDSA65:   {2, 16, 840, 1, 101, 3, 4, 3, 18},
+	KeyTypeMLDSA87:   {2, 16, 840, 1, 101, 3, 4, 3, 19},
+}
+
+// subjectPublicKeyInfo is the X.509 SubjectPublicKeyInfo structure (RFC 5280).
+type subjectPublicKeyInfo struct {
+	Algorithm pkix.AlgorithmIdentifier
+	PublicKey asn1.BitString
+}
+
+// privateKeyInfo is the PKCS #8 PrivateKeyInfo structure (RFC 5208).
+type privateKeyInfo struct {
+	Version    int
+	Algorithm  pkix.AlgorithmIdentifier
+	PrivateKey []byte
+}
+
+// HasStandardEncoding reports whether keys of this type can be encoded with
+// MarshalPKIXPublicKey and MarshalPKCS8PrivateKey.
+func (t KeyType) HasStandardEncoding() bool {
+	_, ok := keyTypeOIDs[t]
+	return ok
+}
+
+// oid returns the algorithm identifier of a key type, or ErrNoStandardEncoding.
+func (t KeyType) oid() (asn1.ObjectIdentifier, error) {
+	oid, ok := keyTypeOIDs[t]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNoStandardEncoding, t)
+	}
+	return oid, nil
+}
+
+// MarshalPKIXPublicKey encodes a public key as a DER SubjectPublicKeyInfo. The
+// subjectPublicKey holds the FIPS 203 encapsulation key or FIPS 204 public key
+// unchanged, as specified for ML-KEM and ML-DSA in X.509.
+func MarshalPKIXPublicKey(keyType KeyType, pubKey []byte) ([]byte, error) {
+	oid, err := keyType.oid()
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(subjectPublicKeyInfo{
+		Algorithm: pkix.AlgorithmIdentifier{Algorithm: oid},
+		PublicKey: asn1.BitString{Bytes: pubKey, BitLength: 8 * len(pubKey)},
+	})
+}
+
+// MarshalPKCS8PrivateKey encodes a private key as a DER PKCS #8 PrivateKeyInfo. The
+// privateKey holds the expandedKey choice: an OCTET STRING with the FIPS 203
+// decapsulation key or FIPS 204 private key.
+func MarshalPKCS8PrivateKey(keyType KeyType, privKey []byte) ([]byte, error) {
+	oid, err := keyType.oid()
+	if err != nil {
+		return nil, err
+	}
+	expanded, err := asn1.Marshal(privKey)
+	if err != nil {
+		return nil, fmt.Errorf("kyber: failed to encode private key: %w", err)
+	}
+	return asn1.Marshal(privateKeyInfo{
+		Algorithm:  pkix.AlgorithmIdentifier{Algorithm: oid},
+		PrivateKey: expanded,
+	})
+}
+
+// ParsePKCS8PrivateKey decodes a DER PKCS #8 PrivateKeyInfo produced by
+// MarshalPKCS8PrivateKey into the key type and the raw private key.
+func ParsePKCS8PrivateKey(der []byte) (KeyType, []byte, error) {
+	var info privateKeyInfo
+	if rest, err := asn1.Unmarshal(der, &info); err != nil || len(rest) > 0 {
+		return "", nil, errors.New("kyber: invalid PKCS #8 private key")
+	}
+	var privKey []byte
+	if rest, err := asn1.Unmarshal(info.PrivateKey, &privKey); err != nil || len(rest) > 0 {
+		return "", nil, errors.New("kyber: PKCS #8 private key is not an expanded key")
+	}
+	for t, oid := range keyTypeOIDs {
+		if oid.Equal(info.Algorithm.Algorithm) {
+			return t, privKey, nil
+		}
+	}
+	return "", nil, fmt.Errorf("kyber: unsupported private key algorithm %s", info.Algorithm.Algorithm)
+}
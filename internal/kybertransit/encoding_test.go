@@ -0,0 +1,62 @@
+package kybertransit
+
+import (
+	"crypto/mlkem"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyEncoding_TableDriven(t *testing.T) {
+	tests := []struct {
+		keyType KeyType
+		// DER prefix of the SubjectPublicKeyInfo up to the key bytes, as in the
+		// ML-KEM and ML-DSA X.509 specifications.
+		wantSPKIPrefix string
+	}{
+		{KeyTypeMLKEM512, "3082033230" + "0b0609608648016503040401" + "0382032100"},
+		{KeyTypeMLKEM768, "308204b230" + "0b0609608648016503040402" + "038204a100"},
+		{KeyTypeMLKEM1024, "3082063230" + "0b0609608648016503040403" + "0382062100"},
+		{KeyTypeMLDSA44, "3082053230" + "0b0609608648016503040311" + "0382052100"},
+		{KeyTypeMLDSA65, "308207b230" + "0b0609608648016503040312" + "038207a100"},
+		{KeyTypeMLDSA87, "30820a3230" + "0b0609608648016503040313" + "03820a2100"},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.keyType), func(t *testing.T) {
+			assert.True(t, tt.keyType.HasStandardEncoding())
+			kp, err := GenerateKeyPair(tt.keyType)
+			require.NoError(t, err)
+
+			spki, err := MarshalPKIXPublicKey(tt.keyType, kp.PublicKey)
+			require.NoError(t, err)
+			prefix, err := hex.DecodeString(tt.wantSPKIPrefix)
+			require.NoError(t, err)
+			assert.Equal(t, append(prefix, kp.PublicKey...), spki)
+
+			der, err := MarshalPKCS8PrivateKey(tt.keyType, kp.PrivateKey)
+			require.NoError(t, err)
+			keyType, privKey, err := ParsePKCS8PrivateKey(der)
+			require.NoError(t, err)
+			assert.Equal(t, tt.keyType, keyType)
+			assert.Equal(t, kp.PrivateKey, privKey)
+		})
+	}
+
+	// The encapsulation key inside the SPKI is the plain FIPS 203 encoding.
+	kp, err := GenerateKeyPair(KeyTypeMLKEM1024)
+	require.NoError(t, err)
+	_, err = mlkem.NewEncapsulationKey1024(kp.PublicKey)
+	assert.NoError(t, err)
+
+	for _, keyType := range []KeyType{KeyTypeKyber768, KeyTypeX25519MLKEM768} {
+		assert.False(t, keyType.HasStandardEncoding())
+		_, err := MarshalPKIXPublicKey(keyType, []byte("pub"))
+		assert.ErrorIs(t, err, ErrNoStandardEncoding)
+		_, err = MarshalPKCS8PrivateKey(keyType, []byte("priv"))
+		assert.ErrorIs(t, err, ErrNoStandardEncoding)
+	}
+	_, _, err = ParsePKCS8PrivateKey([]byte("not der"))
+	assert.ErrorContains(t, err, "invalid PKCS #8 private key")
+}
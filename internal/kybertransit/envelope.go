@@ -0,0 +1,222 @@
+package kybertransit
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// EnvelopePrefix starts kyber1024 ciphertext tokens and legacy two-field ciphertexts.
+// The full form of a token is "<label>:v<key version>:<base64 payload>", where the
+// label is "kyber" for kyber1024 and the key type (e.g. "ml-kem-768") for every other
+// type. Tokens issued before the key type was shown carry "kyber" for any type and are
+// still accepted.
+const EnvelopePrefix = "kyber:v"
+
+// legacyEnvelopeLabel is the label of EnvelopePrefix.
+const legacyEnvelopeLabel = "kyber"
+
+// envelopeFormat is the version of the binary payload layout.
+const envelopeFormat = 1
+
+// envelopeHeaderSize is format(1) + algorithm(1) + key version(4) + KEM ciphertext length(2).
+const envelopeHeaderSize = 8
+
+// Algorithm identifies the KEM and DEM combination used to produce an envelope.
+type Algorithm uint8
+
+const (
+	// AlgorithmKyber1024AES256GCM is Kyber-1024, HKDF-SHA256 and AES-256-GCM.
+	AlgorithmKyber1024AES256GCM Algorithm = 1
+	// AlgorithmKyber512AES256GCM is Kyber-512, HKDF-SHA256 and AES-256-GCM.
+	AlgorithmKyber512AES256GCM Algorithm = 2
+	// AlgorithmKyber768AES256GCM is Kyber-768, HKDF-SHA256 and AES-256-GCM.
+	AlgorithmKyber768AES256GCM Algorithm = 3
+	// AlgorithmMLKEM512AES256GCM is ML-KEM-512, HKDF-SHA256 and AES-256-GCM.
+	AlgorithmMLKEM512AES256GCM Algorithm = 4
+	// AlgorithmMLKEM768AES256GCM is ML-KEM-768, HKDF-SHA256 and AES-256-GCM.
+	AlgorithmMLKEM768AES256GCM Algorithm = 5
+	// AlgorithmMLKEM1024AES256GCM is ML-KEM-1024, HKDF-SHA256 and AES-256-GCM.
+	AlgorithmMLKEM1024AES256GCM Algorithm = 6
+	// AlgorithmX25519MLKEM768AES256GCM is X-Wing (X25519 + ML-KEM-768), HKDF-SHA256 and AES-256-GCM.
+	AlgorithmX25519MLKEM768AES256GCM Algorithm = 7
+)
+
+// String returns a human-readable algorithm name.
+func (a Algorithm) String() string {
+	switch a {
+	case AlgorithmKyber1024AES256GCM:
+		return "kyber1024-hkdf-sha256-aes256gcm"
+	case AlgorithmKyber512AES256GCM:
+		return "kyber512-hkdf-sha256-aes256gcm"
+	case AlgorithmKyber768AES256GCM:
+		return "kyber768-hkdf-sha256-aes256gcm"
+	case AlgorithmMLKEM512AES256GCM:
+		return "ml-kem-512-hkdf-sha256-aes256gcm"
+	case AlgorithmMLKEM768AES256GCM:
+		return "ml-kem-768-hkdf-sha256-aes256gcm"
+	case AlgorithmMLKEM1024AES256GCM:
+		return "ml-kem-1024-hkdf-sha256-aes256gcm"
+	case AlgorithmX25519MLKEM768AES256GCM:
+		return "x25519-ml-kem-768-hkdf-sha256-aes256gcm"
+	default:
+		return "unknown(" + strconv.Itoa(int(a)) + ")"
+	}
+}
+
+// label returns the visible token label of the algorithm: "kyber" for kyber1024, so
+// existing tokens keep their form, and the key type name otherwise.
+func (a Algorithm) label() string {
+	t, ok := a.KeyType()
+	if !ok || t == KeyTypeKyber1024 {
+		return legacyEnvelopeLabel
+	}
+	return string(t)
+}
+
+// KeyType returns the key type whose ciphertexts use this algorithm.
+// The boolean result is false for unknown algorithms.
+func (a Algorithm) KeyType() (KeyType, bool) {
+	for t, info := range keyTypes {
+		if info.algorithm == a {
+			return t, true
+		}
+	}
+	return "", false
+}
+
+// Envelope is the decoded form of a self-describing ciphertext token.
+//
+// The base64 payload of the token is laid out as:
+//
+//	format(1) | algorithm(1) | key version(4, BE) | KEM ct length(2, BE) | KEM ct | nonce(12) | sealed data
+type Envelope struct {
+	Algorithm     Algorithm // KEM/DEM combination
+	KeyVersion    int       // Version of the named key used for encapsulation
+	KEMCiphertext []byte    // KEM ciphertext (Kyber, ML-KEM or hybrid)
+	Nonce         []byte    // AES-GCM nonce
+	Data          []byte    // Sealed plaintext including the GCM tag
+}
+
+// String encodes the envelope as a "<label>:v<version>:<base64>" token.
+func (e Envelope) String() string {
+	buf := make([]byte, envelopeHeaderSize, envelopeHeaderSize+len(e.KEMCiphertext)+len(e.Nonce)+len(e.Data))
+	buf[0] = envelopeFormat
+	buf[1] = byte(e.Algorithm)
+	binary.BigEndian.PutUint32(buf[2:6], uint32(e.KeyVersion))
+	binary.BigEndian.PutUint16(buf[6:8], uint16(len(e.KEMCiphertext)))
+	buf = append(buf, e.KEMCiphertext...)
+	buf = append(buf, e.Nonce...)
+	buf = append(buf, e.Data...)
+	return e.Algorithm.label() + ":v" + strconv.Itoa(e.KeyVersion) + ":" + base64.StdEncoding.EncodeToString(buf)
+}
+
+// ParseEnvelope decodes a "<label>:v<version>:<base64>" token.
+// The key version in the prefix must match the one packed in the payload, and a key
+// type label must match the payload algorithm; the legacy "kyber" label is accepted
+// for every algorithm.
+func ParseEnvelope(token string) (Envelope, error) {
+	label, prefixed, ok := strings.Cut(token, ":")
+	if !ok || !strings.HasPrefix(prefixed, "v") {
+		return Envelope{}, errors.New("kyber: ciphertext is not an envelope")
+	}
+	version, b64, err := cutVersion(prefixed[1:])
+	if err != nil {
+		return Envelope{}, err
+	}
+	buf, err := base64.StdEncoding.DecodeString(b64)
+	if err != nil {
+		return Envelope{}, fmt.Errorf("kyber: invalid base64 envelope: %w", err)
+	}
+	if len(buf) < envelopeHeaderSize {
+		return Envelope{}, errors.New("kyber: envelope is too short")
+	}
+	if buf[0] != envelopeFormat {
+		return Envelope{}, fmt.Errorf("kyber: unsupported envelope format %d", buf[0])
+	}
+	env := Envelope{
+		Algorithm:  Algorithm(buf[1]),
+		KeyVersion: int(binary.BigEndian.Uint32(buf[2:6])),
+	}
+	if _, ok := env.Algorithm.KeyType(); !ok {
+		return Envelope{}, fmt.Errorf("kyber: unsupported envelope algorithm %s", env.Algorithm)
+	}
+	if label != legacyEnvelopeLabel && label != env.Algorithm.label() {
+		return Envelope{}, fmt.Errorf("kyber: envelope label %q does not match algorithm %s", label, env.Algorithm)
+	}
+	if env.KeyVersion != version {
+		return Envelope{}, fmt.Errorf("kyber: envelope key version %d does not match prefix version %d", env.KeyVersion, version)
+	}
+	ctLen := int(binary.BigEndian.Uint16(buf[6:8]))
+	rest := buf[envelopeHeaderSize:]
+	if len(rest) < ctLen+nonceSize {
+		return Envelope{}, errors.New("kyber: envelope is too short")
+	}
+	env.KEMCiphertext = rest[:ctLen]
+	env.Nonce = rest[ctLen : ctLen+nonceSize]
+	env.Data = rest[ctLen+nonceSize:]
+	return env, nil
+}
+
+// SplitVersionPrefix splits a "kyber:v<version>:<rest>" string into version and rest.
+// Strings without the prefix predate key versioning and are returned unchanged as version 1.
+func SplitVersionPrefix(s string) (int, string, error) {
+	if !strings.HasPrefix(s, EnvelopePrefix) {
+		return 1, s, nil
+	}
+	return cutVersion(strings.TrimPrefix(s, EnvelopePrefix))
+}
+
+// cutVersion splits a "<version>:<rest>" string into version and rest.
+func cutVersion(s string) (int, string, error) {
+	v, rest, ok := strings.Cut(s, ":")
+	if !ok {
+		return 0, "", errors.New("kyber: missing key version separator")
+	}
+	version, err := strconv.Atoi(v)
+	if err != nil || version < 1 {
+		return 0, "", fmt.Errorf("kyber: invalid key version %q", v)
+	}
+	return version, rest, nil
+}
+
+// EncryptEnvelope encrypts plaintext with the given public key of type keyType and
+// returns a self-describing token that records the algorithm and key version.
+// associatedData (may be nil) is authenticated but neither encrypted nor stored in the
+// token, and a non-nil context derives the DEM key as described for Encrypt; the same
+// values must be passed to DecryptEnvelope.
+func EncryptEnvelope(keyType KeyType, pubKey []byte, keyVersion int, plaintext []byte, associatedData []byte, context []byte) (string, error) {
+	info, err := keyType.info()
+	if err != nil {
+		return "", err
+	}
+	kemCT, nonce, sealed, err := encrypt(info, pubKey, plaintext, associatedData, context)
+	if err != nil {
+		return "", err
+	}
+	env := Envelope{
+		Algorithm:     info.algorithm,
+		KeyVersion:    keyVersion,
+		KEMCiphertext: kemCT,
+		Nonce:         nonce,
+		Data:          sealed,
+	}
+	return env.String(), nil
+}
+
+// DecryptEnvelope decrypts a parsed envelope with the private key of its key version.
+// The envelope algorithm must match keyType, and associatedData and context must equal
+// the values given to EncryptEnvelope; otherwise ErrAuthentication is returned.
+func DecryptEnvelope(keyType KeyType, privKey []byte, env Envelope, associatedData []byte, context []byte) ([]byte, error) {
+	info, err := keyType.info()
+	if err != nil {
+		return nil, err
+	}
+	if env.Algorithm != info.algorithm {
+		return nil, fmt.Errorf("kyber: envelope algorithm %s does not match key type %s", env.Algorithm, keyType)
+	}
+	return decrypt(info, privKey, env.KEMCiphertext, env.Nonce, env.Data, associatedData, context)
+}
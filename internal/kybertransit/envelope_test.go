@@ -0,0 +1,168 @@
+package kybertransit
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnvelopeEncryptDecrypt(t *testing.T) {
+	kp, err := GenerateKeyPair(KeyTypeKyber1024)
+	require.NoError(t, err)
+
+	token, err := EncryptEnvelope(KeyTypeKyber1024, kp.PublicKey, 3, []byte("envelope message"), nil, nil)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(token, "kyber:v3:"))
+
+	env, err := ParseEnvelope(token)
+	require.NoError(t, err)
+	assert.Equal(t, AlgorithmKyber1024AES256GCM, env.Algorithm)
+	assert.Equal(t, 3, env.KeyVersion)
+	assert.Len(t, env.Nonce, nonceSize)
+	assert.Equal(t, token, env.String())
+
+	pt, err := DecryptEnvelope(KeyTypeKyber1024, kp.PrivateKey, env, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("envelope message"), pt)
+
+	env.Data[0] ^= 0x01
+	_, err = DecryptEnvelope(KeyTypeKyber1024, kp.PrivateKey, env, nil, nil)
+	assert.ErrorIs(t, err, ErrAuthentication)
+}
+
+func TestEnvelopeAssociatedData_TableDriven(t *testing.T) {
+	kp, err := GenerateKeyPair(KeyTypeMLKEM768)
+	require.NoError(t, err)
+	token, err := EncryptEnvelope(KeyTypeMLKEM768, kp.PublicKey, 1, []byte("card number"), []byte("customer-a"), nil)
+	require.NoError(t, err)
+	env, err := ParseEnvelope(token)
+	require.NoError(t, err)
+	assert.NotContains(t, string(env.Data), "customer-a", "associated data is not stored in the ciphertext")
+
+	tests := []struct {
+		name           string
+		associatedData []byte
+		wantErr        bool
+	}{
+		{"same associated data", []byte("customer-a"), false},
+		{"other associated data", []byte("customer-b"), true},
+		{"missing associated data", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pt, err := DecryptEnvelope(KeyTypeMLKEM768, kp.PrivateKey, env, tt.associatedData, nil)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrAuthentication)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, []byte("card number"), pt)
+		})
+	}
+}
+
+func TestEnvelopeLabel_TableDriven(t *testing.T) {
+	kp, err := GenerateKeyPair(KeyTypeMLKEM768)
+	require.NoError(t, err)
+	token, err := EncryptEnvelope(KeyTypeMLKEM768, kp.PublicKey, 2, []byte("labelled"), nil, nil)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(token, "ml-kem-768:v2:"))
+	payload := strings.TrimPrefix(token, "ml-kem-768:v2:")
+
+	tests := []struct {
+		name      string
+		token     string
+		wantError string
+	}{
+		{"key type label", token, ""},
+		{"legacy kyber label", "kyber:v2:" + payload, ""},
+		{"other key type label", "ml-kem-512:v2:" + payload, "does not match algorithm"},
+		{"unknown label", "aes:v2:" + payload, "does not match algorithm"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env, err := ParseEnvelope(tt.token)
+			if tt.wantError != "" {
+				assert.ErrorContains(t, err, tt.wantError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, AlgorithmMLKEM768AES256GCM, env.Algorithm)
+			assert.Equal(t, token, env.String(), "tokens are always re-encoded with the key type label")
+		})
+	}
+
+	kyberKey, err := GenerateKeyPair(KeyTypeKyber1024)
+	require.NoError(t, err)
+	kyberToken, err := EncryptEnvelope(KeyTypeKyber1024, kyberKey.PublicKey, 1, []byte("legacy form"), nil, nil)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(kyberToken, "kyber:v1:"), "kyber1024 keeps the kyber label")
+}
+
+func TestParseEnvelopeErrors_TableDriven(t *testing.T) {
+	kp, err := GenerateKeyPair(KeyTypeKyber1024)
+	require.NoError(t, err)
+	token, err := EncryptEnvelope(KeyTypeKyber1024, kp.PublicKey, 1, []byte("data"), nil, nil)
+	require.NoError(t, err)
+	payload, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(token, "kyber:v1:"))
+	require.NoError(t, err)
+
+	withByte := func(i int, b byte) string {
+		p := append([]byte(nil), payload...)
+		p[i] = b
+		return "kyber:v1:" + base64.StdEncoding.EncodeToString(p)
+	}
+
+	tests := []struct {
+		name      string
+		token     string
+		wantError string
+	}{
+		{"no prefix", base64.StdEncoding.EncodeToString(payload), "not an envelope"},
+		{"missing separator", "kyber:v1", "missing key version separator"},
+		{"invalid version", "kyber:v0:abc", "invalid key version"},
+		{"invalid base64", "kyber:v1:!!!", "invalid base64"},
+		{"too short", "kyber:v1:" + base64.StdEncoding.EncodeToString([]byte{1, 1}), "too short"},
+		{"unknown format", withByte(0, 9), "unsupported envelope format"},
+		{"unknown algorithm", withByte(1, 0), "unsupported envelope algorithm"},
+		{"version mismatch", strings.Replace(token, "kyber:v1:", "kyber:v2:", 1), "does not match"},
+		{"truncated payload", "kyber:v1:" + base64.StdEncoding.EncodeToString(payload[:envelopeHeaderSize+4]), "too short"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseEnvelope(tt.token)
+			assert.ErrorContains(t, err, tt.wantError)
+		})
+	}
+}
+
+func TestSplitVersionPrefix_TableDriven(t *testing.T) {
+	tests := []struct {
+		name        string
+		in          string
+		wantVersion int
+		wantRest    string
+		wantErr     bool
+	}{
+		{"unprefixed", "abc", 1, "abc", false},
+		{"prefixed", "kyber:v12:abc", 12, "abc", false},
+		{"bad version", "kyber:vX:abc", 0, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, rest, err := SplitVersionPrefix(tt.in)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantVersion, v)
+			assert.Equal(t, tt.wantRest, rest)
+		})
+	}
+}
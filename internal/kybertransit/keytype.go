@@ -0,0 +1,121 @@
+package kybertransit
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/cloudflare/circl/kem"
+	"github.com/cloudflare/circl/kem/kyber/kyber1024"
+	"github.com/cloudflare/circl/kem/kyber/kyber512"
+	"github.com/cloudflare/circl/kem/kyber/kyber768"
+	"github.com/cloudflare/circl/kem/mlkem/mlkem1024"
+	"github.com/cloudflare/circl/kem/mlkem/mlkem512"
+	"github.com/cloudflare/circl/kem/mlkem/mlkem768"
+	"github.com/cloudflare/circl/kem/xwing"
+)
+
+// KeyType names the KEM or signature parameter set a key pair is generated for.
+//
+// The kyber* types are round-3 CRYSTALS-Kyber; the ml-kem-* types are the
+// standardized ML-KEM of FIPS 203. The two are not byte-compatible.
+// KeyTypeX25519MLKEM768 is a hybrid that stays secure as long as either of its
+// halves is unbroken.
+type KeyType string
+
+const (
+	// KeyTypeKyber512 is Kyber-512 (NIST security level 1).
+	KeyTypeKyber512 KeyType = "kyber512"
+	// KeyTypeKyber768 is Kyber-768 (NIST security level 3).
+	KeyTypeKyber768 KeyType = "kyber768"
+	// KeyTypeKyber1024 is Kyber-1024 (NIST security level 5).
+	KeyTypeKyber1024 KeyType = "kyber1024"
+	// KeyTypeMLKEM512 is ML-KEM-512 (FIPS 203, security category 1).
+	KeyTypeMLKEM512 KeyType = "ml-kem-512"
+	// KeyTypeMLKEM768 is ML-KEM-768 (FIPS 203, security category 3).
+	KeyTypeMLKEM768 KeyType = "ml-kem-768"
+	// KeyTypeMLKEM1024 is ML-KEM-1024 (FIPS 203, security category 5).
+	KeyTypeMLKEM1024 KeyType = "ml-kem-1024"
+	// KeyTypeX25519MLKEM768 is the X-Wing hybrid KEM: the X25519 and ML-KEM-768 shared
+	// secrets are combined with SHA3-256 over both ciphertexts and the X25519 public key.
+	// Public keys and KEM ciphertexts are the ML-KEM-768 half followed by the 32-byte
+	// X25519 half; the private key is the 32-byte seed both halves are derived from.
+	KeyTypeX25519MLKEM768 KeyType = "x25519-ml-kem-768"
+)
+
+// DefaultKeyType is used when no type is requested, and for keys stored before
+// key types existed.
+const DefaultKeyType = KeyTypeKyber1024
+
+// keyTypeInfo binds a key type to its CIRCL scheme and envelope algorithm.
+type keyTypeInfo struct {
+	scheme    kem.Scheme
+	algorithm Algorithm
+}
+
+// keyTypes lists every supported encryption (KEM) key type.
+// Signing key types are listed in signingKeyTypes.
+var keyTypes = map[KeyType]keyTypeInfo{
+	KeyTypeKyber512:  {kyber512.Scheme(), AlgorithmKyber512AES256GCM},
+	KeyTypeKyber768:  {kyber768.Scheme(), AlgorithmKyber768AES256GCM},
+	KeyTypeKyber1024: {kyber1024.Scheme(), AlgorithmKyber1024AES256GCM},
+	KeyTypeMLKEM512:  {mlkem512.Scheme(), AlgorithmMLKEM512AES256GCM},
+	KeyTypeMLKEM768:  {mlkem768.Scheme(), AlgorithmMLKEM768AES256GCM},
+	KeyTypeMLKEM1024: {mlkem1024.Scheme(), AlgorithmMLKEM1024AES256GCM},
+
+	KeyTypeX25519MLKEM768: {xwing.Scheme(), AlgorithmX25519MLKEM768AES256GCM},
+}
+
+// ParseKeyType validates a key type name. An empty name selects DefaultKeyType.
+func ParseKeyType(s string) (KeyType, error) {
+	if s == "" {
+		return DefaultKeyType, nil
+	}
+	t := KeyType(s)
+	if !t.SupportsEncryption() && !t.SupportsSigning() {
+		return "", fmt.Errorf("kyber: unsupported key type %q", s)
+	}
+	return t, nil
+}
+
+// KeyTypes returns the names of all supported key types, sorted.
+func KeyTypes() []KeyType {
+	types := make([]KeyType, 0, len(keyTypes)+len(signingKeyTypes))
+	for t := range keyTypes {
+		types = append(types, t)
+	}
+	for t := range signingKeyTypes {
+		types = append(types, t)
+	}
+	slices.Sort(types)
+	return types
+}
+
+// SupportsEncryption reports whether keys of this type encrypt and decrypt.
+func (t KeyType) SupportsEncryption() bool {
+	_, ok := keyTypes[t]
+	return ok
+}
+
+// SupportsSigning reports whether keys of this type sign and verify.
+func (t KeyType) SupportsSigning() bool {
+	_, ok := signingKeyTypes[t]
+	return ok
+}
+
+// Algorithm returns the envelope algorithm used for ciphertexts of this key type.
+func (t KeyType) Algorithm() Algorithm {
+	return keyTypes[t].algorithm
+}
+
+// info returns the scheme details of an encryption key type, or an error if the
+// type is unknown or only supports signing.
+func (t KeyType) info() (keyTypeInfo, error) {
+	info, ok := keyTypes[t]
+	if !ok {
+		if t.SupportsSigning() {
+			return keyTypeInfo{}, fmt.Errorf("kyber: key type %q does not support encryption", string(t))
+		}
+		return keyTypeInfo{}, fmt.Errorf("kyber: unsupported key type %q", string(t))
+	}
+	return info, nil
+}
@@ -0,0 +1,153 @@
+package kybertransit
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/cloudflare/circl/kem/mlkem/mlkem768"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyTypes_EncryptDecrypt(t *testing.T) {
+	for _, keyType := range KeyTypes() {
+		if !keyType.SupportsEncryption() {
+			continue
+		}
+		t.Run(string(keyType), func(t *testing.T) {
+			kp, err := GenerateKeyPair(keyType)
+			require.NoError(t, err)
+
+			token, err := EncryptEnvelope(keyType, kp.PublicKey, 1, []byte("parameter set"), nil, nil)
+			require.NoError(t, err)
+			env, err := ParseEnvelope(token)
+			require.NoError(t, err)
+			assert.Equal(t, keyType.Algorithm(), env.Algorithm)
+			gotType, ok := env.Algorithm.KeyType()
+			require.True(t, ok)
+			assert.Equal(t, keyType, gotType)
+			assert.True(t, strings.HasPrefix(env.Algorithm.String(), string(keyType)+"-"))
+
+			pt, err := DecryptEnvelope(keyType, kp.PrivateKey, env, nil, nil)
+			require.NoError(t, err)
+			assert.Equal(t, []byte("parameter set"), pt)
+
+			ct, encdata, err := Encrypt(keyType, kp.PublicKey, []byte("legacy"), nil)
+			require.NoError(t, err)
+			pt, err = Decrypt(keyType, kp.PrivateKey, ct, encdata, nil)
+			require.NoError(t, err)
+			assert.Equal(t, []byte("legacy"), pt)
+		})
+	}
+}
+
+func TestKeyTypes_Mismatch(t *testing.T) {
+	small, err := GenerateKeyPair(KeyTypeKyber512)
+	require.NoError(t, err)
+	large, err := GenerateKeyPair(KeyTypeKyber1024)
+	require.NoError(t, err)
+	assert.Less(t, len(small.PublicKey), len(large.PublicKey))
+
+	_, err = EncryptEnvelope(KeyTypeKyber1024, small.PublicKey, 1, []byte("data"), nil, nil)
+	assert.ErrorContains(t, err, "unmarshal public key")
+
+	token, err := EncryptEnvelope(KeyTypeKyber512, small.PublicKey, 1, []byte("data"), nil, nil)
+	require.NoError(t, err)
+	env, err := ParseEnvelope(token)
+	require.NoError(t, err)
+	_, err = DecryptEnvelope(KeyTypeKyber1024, large.PrivateKey, env, nil, nil)
+	assert.ErrorContains(t, err, "does not match key type")
+}
+
+func TestKeyTypes_MLKEMIsNotKyber(t *testing.T) {
+	kp, err := GenerateKeyPair(KeyTypeMLKEM768)
+	require.NoError(t, err)
+
+	// Kyber-768 and ML-KEM-768 keys have the same size, so the key bytes parse
+	// under both schemes, but the shared secrets differ.
+	ct, encdata, err := Encrypt(KeyTypeKyber768, kp.PublicKey, []byte("data"), nil)
+	require.NoError(t, err)
+	_, err = Decrypt(KeyTypeMLKEM768, kp.PrivateKey, ct, encdata, nil)
+	assert.ErrorContains(t, err, "authentication failed")
+
+	token, err := EncryptEnvelope(KeyTypeMLKEM768, kp.PublicKey, 1, []byte("data"), nil, nil)
+	require.NoError(t, err)
+	env, err := ParseEnvelope(token)
+	require.NoError(t, err)
+	assert.Equal(t, AlgorithmMLKEM768AES256GCM, env.Algorithm)
+	_, err = DecryptEnvelope(KeyTypeKyber768, kp.PrivateKey, env, nil, nil)
+	assert.ErrorContains(t, err, "does not match key type")
+}
+
+func TestKeyTypes_HybridHalves(t *testing.T) {
+	kp, err := GenerateKeyPair(KeyTypeX25519MLKEM768)
+	require.NoError(t, err)
+	require.Len(t, kp.PublicKey, mlkem768.PublicKeySize+32)
+	assert.Len(t, kp.PrivateKey, 32)
+
+	// The public key is the ML-KEM-768 key followed by the X25519 key.
+	_, err = mlkem768.Scheme().UnmarshalBinaryPublicKey(kp.PublicKey[:mlkem768.PublicKeySize])
+	assert.NoError(t, err)
+
+	token, err := EncryptEnvelope(KeyTypeX25519MLKEM768, kp.PublicKey, 1, []byte("hybrid"), nil, nil)
+	require.NoError(t, err)
+	env, err := ParseEnvelope(token)
+	require.NoError(t, err)
+	require.Len(t, env.KEMCiphertext, mlkem768.CiphertextSize+32)
+	pt, err := DecryptEnvelope(KeyTypeX25519MLKEM768, kp.PrivateKey, env, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("hybrid"), pt)
+
+	// Both halves of the KEM ciphertext feed the combined shared secret.
+	tests := []struct {
+		name  string
+		index int
+	}{
+		{"ML-KEM half", 0},
+		{"X25519 half", mlkem768.CiphertextSize},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tampered := env
+			tampered.KEMCiphertext = append([]byte(nil), env.KEMCiphertext...)
+			tampered.KEMCiphertext[tt.index] ^= 0x01
+			_, err := DecryptEnvelope(KeyTypeX25519MLKEM768, kp.PrivateKey, tampered, nil, nil)
+			assert.ErrorContains(t, err, "authentication failed")
+		})
+	}
+}
+
+func TestParseKeyType_TableDriven(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    KeyType
+		wantErr bool
+	}{
+		{"default", "", KeyTypeKyber1024, false},
+		{"kyber512", "kyber512", KeyTypeKyber512, false},
+		{"kyber768", "kyber768", KeyTypeKyber768, false},
+		{"kyber1024", "kyber1024", KeyTypeKyber1024, false},
+		{"ml-kem-512", "ml-kem-512", KeyTypeMLKEM512, false},
+		{"ml-kem-768", "ml-kem-768", KeyTypeMLKEM768, false},
+		{"ml-kem-1024", "ml-kem-1024", KeyTypeMLKEM1024, false},
+		{"hybrid", "x25519-ml-kem-768", KeyTypeX25519MLKEM768, false},
+		{"ml-dsa-65", "ml-dsa-65", KeyTypeMLDSA65, false},
+		{"unknown", "rsa2048", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseKeyType(tt.in)
+			if tt.wantErr {
+				assert.ErrorContains(t, err, "unsupported key type")
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	_, err := GenerateKeyPair("rsa2048")
+	assert.ErrorContains(t, err, "unsupported key type")
+}
@@ -1,25 +1,52 @@
 package kybertransit
 
 import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
 	"errors"
 	"fmt"
-
-	"github.com/cloudflare/circl/kem/kyber/kyber1024"
 )
 
-// KeyPair holds a Kyber public and private key in binary form.
+// demKeyInfoPrefix starts the HKDF info string that binds derived DEM keys to this
+// construction. It is followed by the algorithm name, e.g. "kyber1024-hkdf-sha256-aes256gcm".
+const demKeyInfoPrefix = "kybertransit/v1 "
+
+// derivedKeyInfoSeparator follows the algorithm name in the HKDF info of derived DEM keys,
+// and is followed by the derivation context. Algorithm names never contain a NUL byte.
+const derivedKeyInfoSeparator = " derived\x00"
+
+// demKeySize is the size of the AES-256-GCM key derived from the Kyber shared secret.
+const demKeySize = 32
+
+// nonceSize is the size of the AES-GCM nonce.
+const nonceSize = 12
+
+// ErrAuthentication is returned when the sealed data fails AEAD authentication: the
+// ciphertext was modified, the key is wrong, or the associated data does not match.
+var ErrAuthentication = errors.New("kyber: message authentication failed")
+
+// KeyPair holds a public and private key in binary form.
 // Use GenerateKeyPair to create a new key pair.
 type KeyPair struct {
-	PublicKey  []byte // Serialized Kyber public key
-	PrivateKey []byte // Serialized Kyber private key
+	PublicKey  []byte `json:"public_key"`  // Serialized public key
+	PrivateKey []byte `json:"private_key"` // Serialized private key
 }
 
-// GenerateKeyPair generates a new Kyber-1024 key pair using the CIRCL library.
+// GenerateKeyPair generates a new key pair of the given type using the CIRCL library.
 // Returns a KeyPair with serialized keys, or error on failure.
-func GenerateKeyPair() (KeyPair, error) {
-	scheme := kyber1024.Scheme()
-	pk, sk, err := scheme.GenerateKeyPair()
+func GenerateKeyPair(keyType KeyType) (KeyPair, error) {
+	if keyType.SupportsSigning() {
+		return generateSigningKeyPair(keyType)
+	}
+	info, err := keyType.info()
+	if err != nil {
+		return KeyPair{}, err
+	}
+	pk, sk, err := info.scheme.GenerateKeyPair()
 	if err != nil {
 		return KeyPair{}, fmt.Errorf("kyber: failed to generate key pair: %w", err)
 	}
@@ -34,65 +61,123 @@ func GenerateKeyPair() (KeyPair, error) {
 	return KeyPair{PublicKey: pub, PrivateKey: priv}, nil
 }
 
-// Encrypt encrypts plaintext using the given Kyber public key.
-// Returns base64-encoded ciphertext and encrypted data (encdata).
+// Encrypt encrypts plaintext using the given Kyber public key of type keyType.
+// Returns base64-encoded ciphertext (the KEM ciphertext) and encrypted data (encdata).
 //
-// SECURITY WARNING: For demonstration, plaintext is XORed with the Kyber shared secret.
-// This is NOT secure for production! Use authenticated encryption (e.g., KEM-DEM with AEAD) in real systems.
-func Encrypt(pubKey []byte, plaintext []byte) (string, string, error) {
-	scheme := kyber1024.Scheme()
-	pk, err := scheme.UnmarshalBinaryPublicKey(pubKey)
-	if err != nil || pk == nil {
-		return "", "", fmt.Errorf("kyber: failed to unmarshal public key: %w", err)
+// The scheme is KEM-DEM: the Kyber shared secret is expanded with HKDF-SHA256 into an
+// AES-256-GCM key, and encdata holds the random nonce followed by the sealed plaintext.
+// Any modification of ciphertext or encdata is detected by Decrypt.
+// A non-nil context derives the DEM key from the shared secret and the context, so the
+// ciphertext only decrypts with the same context; nil disables derivation.
+func Encrypt(keyType KeyType, pubKey []byte, plaintext []byte, context []byte) (string, string, error) {
+	info, err := keyType.info()
+	if err != nil {
+		return "", "", err
 	}
-	ct, ss, err := scheme.Encapsulate(pk)
+	kemCT, nonce, sealed, err := encrypt(info, pubKey, plaintext, nil, context)
 	if err != nil {
-		return "", "", fmt.Errorf("kyber: encapsulation failed: %w", err)
+		return "", "", err
 	}
-	if len(ss) == 0 {
-		return "", "", errors.New("kyber: shared secret is empty")
+	enc := append(nonce, sealed...)
+	return base64.StdEncoding.EncodeToString(kemCT), base64.StdEncoding.EncodeToString(enc), nil
+}
+
+// Decrypt decrypts base64-encoded ciphertext and encdata using the given Kyber private key of type keyType
+// and the derivation context given to Encrypt (nil if none).
+// Returns the original plaintext bytes, or error if decoding fails or authentication does not pass.
+func Decrypt(keyType KeyType, privKey []byte, b64ct string, b64enc string, context []byte) ([]byte, error) {
+	info, err := keyType.info()
+	if err != nil {
+		return nil, err
+	}
+	ct, err := base64.StdEncoding.DecodeString(b64ct)
+	if err != nil {
+		return nil, fmt.Errorf("kyber: invalid base64 ciphertext: %w", err)
 	}
-	enc := make([]byte, len(plaintext))
-	for i := range plaintext {
-		enc[i] = plaintext[i] ^ ss[i%len(ss)]
+	enc, err := base64.StdEncoding.DecodeString(b64enc)
+	if err != nil {
+		return nil, fmt.Errorf("kyber: invalid base64 encdata: %w", err)
 	}
-	// Allow empty plaintext (empty encdata is valid)
-	return base64.StdEncoding.EncodeToString(ct), base64.StdEncoding.EncodeToString(enc), nil
+	n := min(nonceSize, len(enc))
+	return decrypt(info, privKey, ct, enc[:n], enc[n:], nil, context)
 }
 
-// Decrypt decrypts base64-encoded ciphertext and encdata using the given Kyber private key.
-// Returns the original plaintext, or error.
-//
-// SECURITY WARNING: For demonstration, encdata is XORed with the Kyber shared secret.
-// This is NOT secure for production! Use authenticated encryption (e.g., KEM-DEM with AEAD) in real systems.
-func Decrypt(privKey []byte, b64ct string, b64enc string) (string, error) {
-	ct, err := base64.StdEncoding.DecodeString(b64ct)
+// encrypt encapsulates a fresh shared secret to pubKey and seals plaintext under the DEM key
+// derived from it and context (may be nil), authenticating associatedData (may be nil)
+// without encrypting it.
+// Returns the KEM ciphertext, the random nonce and the sealed plaintext (including the GCM tag).
+func encrypt(info keyTypeInfo, pubKey []byte, plaintext []byte, associatedData []byte, context []byte) ([]byte, []byte, []byte, error) {
+	pk, err := info.scheme.UnmarshalBinaryPublicKey(pubKey)
+	if err != nil || pk == nil {
+		return nil, nil, nil, fmt.Errorf("kyber: failed to unmarshal public key: %w", err)
+	}
+	ct, ss, err := info.scheme.Encapsulate(pk)
 	if err != nil {
-		return "", fmt.Errorf("kyber: invalid base64 ciphertext: %w", err)
+		return nil, nil, nil, fmt.Errorf("kyber: encapsulation failed: %w", err)
 	}
-	if b64enc == "" {
-		// Allow empty encdata (valid for empty plaintext)
-		return "", nil
+	if len(ss) == 0 {
+		return nil, nil, nil, errors.New("kyber: shared secret is empty")
 	}
-	enc, err := base64.StdEncoding.DecodeString(b64enc)
+	aead, err := newDEM(info.algorithm, ss, context)
 	if err != nil {
-		return "", fmt.Errorf("kyber: invalid base64 encdata: %w", err)
+		return nil, nil, nil, err
+	}
+	nonce := make([]byte, nonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, nil, nil, fmt.Errorf("kyber: failed to generate nonce: %w", err)
 	}
-	scheme := kyber1024.Scheme()
-	sk, err := scheme.UnmarshalBinaryPrivateKey(privKey)
+	return ct, nonce, aead.Seal(nil, nonce, plaintext, associatedData), nil
+}
+
+// decrypt decapsulates the shared secret from the KEM ciphertext and opens the sealed plaintext.
+// associatedData and context must equal the values given to encrypt; otherwise
+// ErrAuthentication is returned.
+func decrypt(info keyTypeInfo, privKey []byte, kemCT []byte, nonce []byte, sealed []byte, associatedData []byte, context []byte) ([]byte, error) {
+	sk, err := info.scheme.UnmarshalBinaryPrivateKey(privKey)
 	if err != nil || sk == nil {
-		return "", fmt.Errorf("kyber: failed to unmarshal private key: %w", err)
+		return nil, fmt.Errorf("kyber: failed to unmarshal private key: %w", err)
 	}
-	ss, err := scheme.Decapsulate(sk, ct)
+	ss, err := info.scheme.Decapsulate(sk, kemCT)
 	if err != nil {
-		return "", fmt.Errorf("kyber: decapsulation failed: %w", err)
+		return nil, fmt.Errorf("kyber: decapsulation failed: %w", err)
 	}
 	if len(ss) == 0 {
-		return "", errors.New("kyber: shared secret is empty")
+		return nil, errors.New("kyber: shared secret is empty")
+	}
+	aead, err := newDEM(info.algorithm, ss, context)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != nonceSize || len(sealed) < aead.Overhead() {
+		return nil, errors.New("kyber: encdata is too short")
 	}
-	plaintext := make([]byte, len(enc))
-	for i := range enc {
-		plaintext[i] = enc[i] ^ ss[i%len(ss)]
+	plaintext, err := aead.Open(nil, nonce, sealed, associatedData)
+	if err != nil {
+		return nil, ErrAuthentication
+	}
+	return plaintext, nil
+}
+
+// newDEM expands the KEM shared secret with HKDF-SHA256 and returns an AES-256-GCM AEAD.
+// The algorithm is part of the HKDF info, so a shared secret never yields the same
+// DEM key under two algorithms. A non-nil context is appended to the info, so each
+// context yields its own DEM key; nil and empty contexts are distinct.
+func newDEM(algorithm Algorithm, sharedSecret []byte, context []byte) (cipher.AEAD, error) {
+	info := demKeyInfoPrefix + algorithm.String()
+	if context != nil {
+		info += derivedKeyInfoSeparator + string(context)
+	}
+	key, err := hkdf.Key(sha256.New, sharedSecret, nil, info, demKeySize)
+	if err != nil {
+		return nil, fmt.Errorf("kyber: failed to derive DEM key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("kyber: failed to create AES cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("kyber: failed to create GCM: %w", err)
 	}
-	return string(plaintext), nil
+	return aead, nil
 }
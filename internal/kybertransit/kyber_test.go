@@ -9,37 +9,33 @@ import (
 )
 
 func TestGenerateKeyPair(t *testing.T) {
-	kp, err := GenerateKeyPair()
+	kp, err := GenerateKeyPair(KeyTypeKyber1024)
 	require.NoError(t, err)
 	assert.NotEmpty(t, kp.PublicKey)
 	assert.NotEmpty(t, kp.PrivateKey)
 }
 
 func TestEncryptDecrypt_TableDriven(t *testing.T) {
-	kp, err := GenerateKeyPair()
+	kp, err := GenerateKeyPair(KeyTypeKyber1024)
 	require.NoError(t, err)
 
 	tests := []struct {
-		name              string
-		plaintext         string
-		allowEmptyEncdata bool
+		name      string
+		plaintext []byte
 	}{
-		{"normal message", "test message", false},
-		{"empty message", "", true},
-		{"unicode", "test message", false},
+		{"normal message", []byte("test message")},
+		{"empty message", nil},
+		{"unicode", []byte("тестовое сообщение ✓")},
+		{"binary", []byte{0x00, 0xff, 0xfe, 0x80, 0x00, 0xc3, 0x28}},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			ct, encdata, err := Encrypt(kp.PublicKey, []byte(tt.plaintext))
+			ct, encdata, err := Encrypt(KeyTypeKyber1024, kp.PublicKey, tt.plaintext, nil)
 			require.NoError(t, err)
 			assert.NotEmpty(t, ct)
-			if tt.allowEmptyEncdata {
-				assert.Equal(t, "", encdata)
-			} else {
-				assert.NotEmpty(t, encdata)
-			}
-			pt, err := Decrypt(kp.PrivateKey, ct, encdata)
+			assert.NotEmpty(t, encdata)
+			pt, err := Decrypt(KeyTypeKyber1024, kp.PrivateKey, ct, encdata, nil)
 			require.NoError(t, err)
 			assert.Equal(t, tt.plaintext, pt)
 		})
@@ -47,7 +43,11 @@ func TestEncryptDecrypt_TableDriven(t *testing.T) {
 }
 
 func TestKyberErrors_TableDriven(t *testing.T) {
-	kp, err := GenerateKeyPair()
+	kp, err := GenerateKeyPair(KeyTypeKyber1024)
+	require.NoError(t, err)
+	validCT, validEnc, err := Encrypt(KeyTypeKyber1024, kp.PublicKey, []byte("data"), nil)
+	require.NoError(t, err)
+	otherCT, _, err := Encrypt(KeyTypeKyber1024, kp.PublicKey, []byte("data"), nil)
 	require.NoError(t, err)
 
 	tests := []struct {
@@ -63,17 +63,19 @@ func TestKyberErrors_TableDriven(t *testing.T) {
 		{"Encrypt with invalid key", true, []byte("badkey"), nil, "", "", "unmarshal public key", false},
 		{"Decrypt with invalid base64", false, nil, kp.PrivateKey, "!!!", "!!!", "invalid base64", false},
 		{"Decrypt with wrong ciphertext", false, nil, kp.PrivateKey, base64.StdEncoding.EncodeToString([]byte("badct")), base64.StdEncoding.EncodeToString([]byte("enc")), "decapsulation failed", false},
-		{"Decrypt with empty encdata", false, nil, kp.PrivateKey, base64.StdEncoding.EncodeToString([]byte("badct")), "", "", true},
+		{"Decrypt with empty encdata", false, nil, kp.PrivateKey, validCT, "", "too short", false},
+		{"Decrypt with tampered encdata", false, nil, kp.PrivateKey, validCT, flipLastByte(t, validEnc), "authentication failed", false},
+		{"Decrypt with foreign ciphertext", false, nil, kp.PrivateKey, otherCT, validEnc, "authentication failed", false},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			if tt.encrypt {
-				_, _, err := Encrypt(tt.pubKey, []byte("data"))
+				_, _, err := Encrypt(KeyTypeKyber1024, tt.pubKey, []byte("data"), nil)
 				assert.Error(t, err)
 				assert.Contains(t, err.Error(), tt.wantError)
 			} else {
-				_, err := Decrypt(tt.privKey, tt.ct, tt.encdata)
+				_, err := Decrypt(KeyTypeKyber1024, tt.privKey, tt.ct, tt.encdata, nil)
 				if tt.allowNoError {
 					assert.NoError(t, err)
 				} else {
@@ -84,3 +86,49 @@ func TestKyberErrors_TableDriven(t *testing.T) {
 		})
 	}
 }
+
+// flipLastByte decodes a base64 string, flips the last bit and re-encodes it.
+func flipLastByte(t *testing.T, b64 string) string {
+	t.Helper()
+	raw, err := base64.StdEncoding.DecodeString(b64)
+	require.NoError(t, err)
+	raw[len(raw)-1] ^= 0x01
+	return base64.StdEncoding.EncodeToString(raw)
+}
+
+func TestDerivedContext_TableDriven(t *testing.T) {
+	kp, err := GenerateKeyPair(KeyTypeMLKEM768)
+	require.NoError(t, err)
+	ct, encdata, err := Encrypt(KeyTypeMLKEM768, kp.PublicKey, []byte("tenant data"), []byte("tenant-a"))
+	require.NoError(t, err)
+	token, err := EncryptEnvelope(KeyTypeMLKEM768, kp.PublicKey, 1, []byte("tenant data"), nil, []byte("tenant-a"))
+	require.NoError(t, err)
+	env, err := ParseEnvelope(token)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		context []byte
+		wantErr bool
+	}{
+		{"same context", []byte("tenant-a"), false},
+		{"other context", []byte("tenant-b"), true},
+		{"no context", nil, true},
+		{"empty context", []byte{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pt, err := Decrypt(KeyTypeMLKEM768, kp.PrivateKey, ct, encdata, tt.context)
+			envPT, envErr := DecryptEnvelope(KeyTypeMLKEM768, kp.PrivateKey, env, nil, tt.context)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrAuthentication)
+				assert.ErrorIs(t, envErr, ErrAuthentication)
+				return
+			}
+			require.NoError(t, err)
+			require.NoError(t, envErr)
+			assert.Equal(t, []byte("tenant data"), pt)
+			assert.Equal(t, []byte("tenant data"), envPT)
+		})
+	}
+}
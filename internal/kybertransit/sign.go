@@ -0,0 +1,115 @@
+package kybertransit
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/cloudflare/circl/sign"
+	"github.com/cloudflare/circl/sign/mldsa/mldsa44"
+	"github.com/cloudflare/circl/sign/mldsa/mldsa65"
+	"github.com/cloudflare/circl/sign/mldsa/mldsa87"
+)
+
+const (
+	// KeyTypeMLDSA44 is ML-DSA-44 (FIPS 204, security category 2).
+	KeyTypeMLDSA44 KeyType = "ml-dsa-44"
+	// KeyTypeMLDSA65 is ML-DSA-65 (FIPS 204, security category 3).
+	KeyTypeMLDSA65 KeyType = "ml-dsa-65"
+	// KeyTypeMLDSA87 is ML-DSA-87 (FIPS 204, security category 5).
+	KeyTypeMLDSA87 KeyType = "ml-dsa-87"
+)
+
+// SignaturePrefix starts every signature token. The full form is
+// "sig:v<key version>:<base64 signature>"; the distinct prefix keeps ciphertext tokens
+// from being mistaken for signatures.
+const SignaturePrefix = "sig:v"
+
+// signingKeyTypes lists every supported signing key type.
+var signingKeyTypes = map[KeyType]sign.Scheme{
+	KeyTypeMLDSA44: mldsa44.Scheme(),
+	KeyTypeMLDSA65: mldsa65.Scheme(),
+	KeyTypeMLDSA87: mldsa87.Scheme(),
+}
+
+// signer returns the signature scheme of a signing key type, or an error if the
+// type is unknown or only supports encryption.
+func (t KeyType) signer() (sign.Scheme, error) {
+	scheme, ok := signingKeyTypes[t]
+	if !ok {
+		if t.SupportsEncryption() {
+			return nil, fmt.Errorf("kyber: key type %q does not support signing", string(t))
+		}
+		return nil, fmt.Errorf("kyber: unsupported key type %q", string(t))
+	}
+	return scheme, nil
+}
+
+// generateSigningKeyPair generates a new key pair for a signing key type.
+func generateSigningKeyPair(keyType KeyType) (KeyPair, error) {
+	scheme, err := keyType.signer()
+	if err != nil {
+		return KeyPair{}, err
+	}
+	pk, sk, err := scheme.GenerateKey()
+	if err != nil {
+		return KeyPair{}, fmt.Errorf("kyber: failed to generate signing key pair: %w", err)
+	}
+	pub, err := pk.MarshalBinary()
+	if err != nil {
+		return KeyPair{}, fmt.Errorf("kyber: failed to marshal public key: %w", err)
+	}
+	priv, err := sk.MarshalBinary()
+	if err != nil {
+		return KeyPair{}, fmt.Errorf("kyber: failed to marshal private key: %w", err)
+	}
+	return KeyPair{PublicKey: pub, PrivateKey: priv}, nil
+}
+
+// Sign signs message with the private key of a signing key type and returns a
+// "sig:v<version>:<base64 signature>" token that records the key version.
+func Sign(keyType KeyType, privKey []byte, keyVersion int, message []byte) (string, error) {
+	scheme, err := keyType.signer()
+	if err != nil {
+		return "", err
+	}
+	sk, err := scheme.UnmarshalBinaryPrivateKey(privKey)
+	if err != nil || sk == nil {
+		return "", fmt.Errorf("kyber: failed to unmarshal private key: %w", err)
+	}
+	sig := scheme.Sign(sk, message, nil)
+	return SignaturePrefix + strconv.Itoa(keyVersion) + ":" + base64.StdEncoding.EncodeToString(sig), nil
+}
+
+// ParseSignature decodes a "sig:v<version>:<base64 signature>" token into the
+// key version and the raw signature. Ciphertext tokens are rejected.
+func ParseSignature(token string) (int, []byte, error) {
+	if !strings.HasPrefix(token, SignaturePrefix) {
+		return 0, nil, errors.New("kyber: token is not a signature: missing the \"sig:v<version>:\" prefix")
+	}
+	version, b64, err := cutVersion(strings.TrimPrefix(token, SignaturePrefix))
+	if err != nil {
+		return 0, nil, err
+	}
+	sig, err := base64.StdEncoding.DecodeString(b64)
+	if err != nil {
+		return 0, nil, fmt.Errorf("kyber: invalid base64 signature: %w", err)
+	}
+	return version, sig, nil
+}
+
+// Verify reports whether sig is a valid signature of message under the public key
+// of a signing key type. An error is returned only if the key cannot be used.
+func Verify(keyType KeyType, pubKey []byte, message []byte, sig []byte) (bool, error) {
+	scheme, err := keyType.signer()
+	if err != nil {
+		return false, err
+	}
+	pk, err := scheme.UnmarshalBinaryPublicKey(pubKey)
+	if err != nil || pk == nil {
+		return false, fmt.Errorf("kyber: failed to unmarshal public key: %w", err)
+	}
+	return scheme.Verify(pk, message, sig, nil), nil
+}
@@ -0,0 +1,84 @@
+package kybertransit
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignVerify_TableDriven(t *testing.T) {
+	tests := []struct {
+		name    string
+		keyType KeyType
+	}{
+		{"ML-DSA-44", KeyTypeMLDSA44},
+		{"ML-DSA-65", KeyTypeMLDSA65},
+		{"ML-DSA-87", KeyTypeMLDSA87},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.True(t, tt.keyType.SupportsSigning())
+			assert.False(t, tt.keyType.SupportsEncryption())
+			kp, err := GenerateKeyPair(tt.keyType)
+			require.NoError(t, err)
+
+			token, err := Sign(tt.keyType, kp.PrivateKey, 2, []byte("release.tar.gz"))
+			require.NoError(t, err)
+			assert.True(t, strings.HasPrefix(token, "sig:v2:"))
+
+			version, sig, err := ParseSignature(token)
+			require.NoError(t, err)
+			assert.Equal(t, 2, version)
+
+			valid, err := Verify(tt.keyType, kp.PublicKey, []byte("release.tar.gz"), sig)
+			require.NoError(t, err)
+			assert.True(t, valid)
+
+			valid, err = Verify(tt.keyType, kp.PublicKey, []byte("release.tar.gz.evil"), sig)
+			require.NoError(t, err)
+			assert.False(t, valid)
+
+			sig[0] ^= 0x01
+			valid, err = Verify(tt.keyType, kp.PublicKey, []byte("release.tar.gz"), sig)
+			require.NoError(t, err)
+			assert.False(t, valid)
+		})
+	}
+}
+
+func TestSignErrors_TableDriven(t *testing.T) {
+	kemKey, err := GenerateKeyPair(KeyTypeMLKEM768)
+	require.NoError(t, err)
+	signKey, err := GenerateKeyPair(KeyTypeMLDSA65)
+	require.NoError(t, err)
+
+	_, err = Sign(KeyTypeMLKEM768, kemKey.PrivateKey, 1, []byte("data"))
+	assert.ErrorContains(t, err, "does not support signing")
+	_, err = Sign(KeyTypeMLDSA65, []byte("badkey"), 1, []byte("data"))
+	assert.ErrorContains(t, err, "unmarshal private key")
+	_, err = Verify(KeyTypeMLDSA65, []byte("badkey"), []byte("data"), nil)
+	assert.ErrorContains(t, err, "unmarshal public key")
+	_, err = EncryptEnvelope(KeyTypeMLDSA65, signKey.PublicKey, 1, []byte("data"), nil, nil)
+	assert.ErrorContains(t, err, "does not support encryption")
+
+	tests := []struct {
+		name      string
+		token     string
+		wantError string
+	}{
+		{"no prefix", "abc", "token is not a signature"},
+		{"ciphertext token", "kyber:v1:YQ==", "token is not a signature"},
+		{"missing separator", "sig:v1", "missing key version separator"},
+		{"invalid version", "sig:v0:abc", "invalid key version"},
+		{"invalid base64", "sig:v1:!!!", "invalid base64"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := ParseSignature(tt.token)
+			assert.ErrorContains(t, err, tt.wantError)
+		})
+	}
+}
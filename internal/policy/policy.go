@@ -0,0 +1,211 @@
+// Package policy implements named ACL policies that grant capabilities on
+// request path globs, and their persistence.
+package policy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/dezween/ElevexaCodingChallenge2/internal/storage"
+)
+
+// Capability is an operation a policy rule can grant on a path.
+type Capability string
+
+// Capabilities understood by the authorization middleware.
+const (
+	Create  Capability = "create"
+	Encrypt Capability = "encrypt"
+	Decrypt Capability = "decrypt"
+	Rotate  Capability = "rotate"
+	Read    Capability = "read"
+	Delete  Capability = "delete"
+	List    Capability = "list"
+	Sign    Capability = "sign"
+	Verify  Capability = "verify"
+	Rewrap  Capability = "rewrap"
+	Update  Capability = "update"
+)
+
+// validCapabilities is the set of capabilities accepted in rules.
+var validCapabilities = map[Capability]bool{
+	Create: true, Encrypt: true, Decrypt: true, Rotate: true, Read: true, Delete: true, List: true,
+	Sign: true, Verify: true, Rewrap: true, Update: true,
+}
+
+// RootName is the built-in policy that grants every capability on every path.
+// It cannot be written or deleted.
+const RootName = "root"
+
+// policyPath is the storage path prefix of stored policies.
+const policyPath = "sys/policy/"
+
+// namePattern restricts policy names to a safe, path-free alphabet.
+var namePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+var (
+	// ErrNotFound is returned for unknown policies.
+	ErrNotFound = errors.New("policy: not found")
+	// ErrInvalid is returned for malformed policies.
+	ErrInvalid = errors.New("policy: invalid")
+	// ErrReadOnly is returned when writing or deleting the root policy.
+	ErrReadOnly = errors.New("policy: root policy cannot be modified")
+)
+
+// Rule grants capabilities on request paths matching a glob.
+// Globs use path.Match syntax: "*" matches within a single path segment,
+// e.g. "/transit/encrypt/payments-*".
+type Rule struct {
+	Path         string       `json:"path"`
+	Capabilities []Capability `json:"capabilities"`
+}
+
+// Policy is a named set of rules.
+type Policy struct {
+	Name  string `json:"name"`
+	Rules []Rule `json:"rules"`
+}
+
+// Validate checks the policy name, rule globs and capabilities.
+func (p Policy) Validate() error {
+	if !namePattern.MatchString(p.Name) {
+		return fmt.Errorf("%w: name must contain only letters, digits, '.', '_' and '-'", ErrInvalid)
+	}
+	if p.Name == RootName {
+		return ErrReadOnly
+	}
+	for _, rule := range p.Rules {
+		if rule.Path == "" {
+			return fmt.Errorf("%w: rule path must not be empty", ErrInvalid)
+		}
+		if _, err := path.Match(normalize(rule.Path), ""); err != nil {
+			return fmt.Errorf("%w: bad path glob %q", ErrInvalid, rule.Path)
+		}
+		if len(rule.Capabilities) == 0 {
+			return fmt.Errorf("%w: rule %q grants no capabilities", ErrInvalid, rule.Path)
+		}
+		for _, c := range rule.Capabilities {
+			if !validCapabilities[c] {
+				return fmt.Errorf("%w: unknown capability %q", ErrInvalid, c)
+			}
+		}
+	}
+	return nil
+}
+
+// Allows reports whether any rule of the policy grants capability on requestPath.
+func (p Policy) Allows(requestPath string, capability Capability) bool {
+	requestPath = normalize(requestPath)
+	for _, rule := range p.Rules {
+		if ok, _ := path.Match(normalize(rule.Path), requestPath); !ok {
+			continue
+		}
+		for _, c := range rule.Capabilities {
+			if c == capability {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// normalize strips the leading slash so "/transit/..." and "transit/..." are equivalent.
+func normalize(p string) string {
+	return strings.TrimPrefix(p, "/")
+}
+
+// Store persists policies in a Storage backend.
+type Store struct {
+	storage storage.Storage
+}
+
+// NewStore returns a policy store on top of the given storage.
+func NewStore(s storage.Storage) *Store {
+	return &Store{storage: s}
+}
+
+// Get returns the named policy, or ErrNotFound. The root policy is not stored.
+func (s *Store) Get(name string) (Policy, error) {
+	if !namePattern.MatchString(name) {
+		return Policy{}, ErrNotFound
+	}
+	data, err := s.storage.Get(policyPath + name)
+	if errors.Is(err, storage.ErrNotFound) {
+		return Policy{}, ErrNotFound
+	}
+	if err != nil {
+		return Policy{}, fmt.Errorf("policy: failed to read %q: %w", name, err)
+	}
+	var p Policy
+	if err := json.Unmarshal(data, &p); err != nil {
+		return Policy{}, fmt.Errorf("policy: failed to decode %q: %w", name, err)
+	}
+	return p, nil
+}
+
+// Put validates and stores the policy, replacing any previous version.
+func (s *Store) Put(p Policy) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	data, err := json.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("policy: failed to encode %q: %w", p.Name, err)
+	}
+	if err := s.storage.Put(policyPath+p.Name, data); err != nil {
+		return fmt.Errorf("policy: failed to write %q: %w", p.Name, err)
+	}
+	return nil
+}
+
+// Delete removes the named policy.
+func (s *Store) Delete(name string) error {
+	if name == RootName {
+		return ErrReadOnly
+	}
+	if _, err := s.Get(name); err != nil {
+		return err
+	}
+	if err := s.storage.Delete(policyPath + name); err != nil {
+		return fmt.Errorf("policy: failed to delete %q: %w", name, err)
+	}
+	return nil
+}
+
+// List returns the names of all stored policies, sorted.
+func (s *Store) List() ([]string, error) {
+	paths, err := s.storage.List(policyPath)
+	if err != nil {
+		return nil, fmt.Errorf("policy: failed to list: %w", err)
+	}
+	names := make([]string, len(paths))
+	for i, p := range paths {
+		names[i] = strings.TrimPrefix(p, policyPath)
+	}
+	return names, nil
+}
+
+// Allowed reports whether the named policies grant capability on requestPath.
+// The root policy grants everything; unknown policy names grant nothing.
+func (s *Store) Allowed(names []string, requestPath string, capability Capability) (bool, error) {
+	for _, name := range names {
+		if name == RootName {
+			return true, nil
+		}
+		p, err := s.Get(name)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		if p.Allows(requestPath, capability) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,102 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/dezween/ElevexaCodingChallenge2/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicy_Allows_TableDriven(t *testing.T) {
+	payments := Policy{
+		Name: "payments",
+		Rules: []Rule{
+			{Path: "/transit/encrypt/payments-*", Capabilities: []Capability{Encrypt}},
+			{Path: "transit/keys/payments-*", Capabilities: []Capability{Create, Read}},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		capability Capability
+		want       bool
+	}{
+		{"encrypt own key", "/transit/encrypt/payments-cards", Encrypt, true},
+		{"decrypt own key not granted", "/transit/decrypt/payments-cards", Decrypt, false},
+		{"encrypt other team key", "/transit/encrypt/hr-salaries", Encrypt, false},
+		{"decrypt other team key", "/transit/decrypt/hr-salaries", Decrypt, false},
+		{"rule without leading slash", "/transit/keys/payments-cards", Create, true},
+		{"glob does not cross segments", "/transit/keys/payments-cards/rotate", Create, false},
+		{"capability mismatch", "/transit/keys/payments-cards", Delete, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, payments.Allows(tt.path, tt.capability))
+		})
+	}
+}
+
+func TestPolicy_Validate_TableDriven(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  Policy
+		wantErr error
+	}{
+		{"valid", Policy{Name: "team-a", Rules: []Rule{{Path: "/transit/*/a-*", Capabilities: []Capability{Encrypt, Decrypt}}}}, nil},
+		{"no rules", Policy{Name: "empty"}, nil},
+		{"bad name", Policy{Name: "a/b"}, ErrInvalid},
+		{"empty name", Policy{Name: ""}, ErrInvalid},
+		{"root", Policy{Name: RootName}, ErrReadOnly},
+		{"empty path", Policy{Name: "p", Rules: []Rule{{Path: "", Capabilities: []Capability{Read}}}}, ErrInvalid},
+		{"bad glob", Policy{Name: "p", Rules: []Rule{{Path: "/transit/[", Capabilities: []Capability{Read}}}}, ErrInvalid},
+		{"no capabilities", Policy{Name: "p", Rules: []Rule{{Path: "/transit/*"}}}, ErrInvalid},
+		{"unknown capability", Policy{Name: "p", Rules: []Rule{{Path: "/transit/*", Capabilities: []Capability{"sudo"}}}}, ErrInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Validate()
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestStore(t *testing.T) {
+	s := NewStore(storage.NewMemory())
+	p := Policy{Name: "payments", Rules: []Rule{{Path: "/transit/encrypt/payments-*", Capabilities: []Capability{Encrypt}}}}
+
+	_, err := s.Get("payments")
+	assert.ErrorIs(t, err, ErrNotFound)
+	require.NoError(t, s.Put(p))
+	got, err := s.Get("payments")
+	require.NoError(t, err)
+	assert.Equal(t, p, got)
+
+	names, err := s.List()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"payments"}, names)
+
+	allowed, err := s.Allowed([]string{"missing", "payments"}, "/transit/encrypt/payments-1", Encrypt)
+	require.NoError(t, err)
+	assert.True(t, allowed)
+	allowed, err = s.Allowed([]string{"payments"}, "/transit/decrypt/payments-1", Decrypt)
+	require.NoError(t, err)
+	assert.False(t, allowed)
+	allowed, err = s.Allowed([]string{RootName}, "/anything", Delete)
+	require.NoError(t, err)
+	assert.True(t, allowed)
+	allowed, err = s.Allowed(nil, "/transit/encrypt/payments-1", Encrypt)
+	require.NoError(t, err)
+	assert.False(t, allowed)
+
+	assert.ErrorIs(t, s.Delete(RootName), ErrReadOnly)
+	require.NoError(t, s.Delete("payments"))
+	assert.ErrorIs(t, s.Delete("payments"), ErrNotFound)
+}
@@ -4,19 +4,111 @@ package routes
 //
 // Methods:
 //
-//	POST RouteCreateKey   - Create a new Kyber key pair
-//	POST RouteEncrypt     - Encrypt data with Kyber
-//	POST RouteDecrypt     - Decrypt data with Kyber
+//	POST   RouteCreateKey       - Create a new Kyber key pair
+//	GET    RouteCreateKey       - Read key metadata and public keys
+//	DELETE RouteCreateKey       - Delete a key (requires deletion_allowed)
+//	GET    RouteKeys            - List keys
+//	POST   RouteRotateKey       - Add a new version to a key
+//	POST   RouteKeyConfig       - Update the configuration of a key
+//	POST   RouteTrimKey         - Delete key versions older than min_available_version
+//	POST   RouteEncrypt         - Encrypt data with Kyber
+//	POST   RouteDecrypt         - Decrypt data with Kyber
+//	POST   RouteRewrap          - Re-encrypt ciphertext with the latest key version
+//	POST   RouteDataKey         - Generate a data key wrapped under a key
+//	GET    RouteExportKey       - Export all versions of a public or private key
+//	GET    RouteExportVersion   - Export one version of a public or private key
+//	GET    RouteBackup          - Back up a key with all versions in plaintext
+//	POST   RouteRestore         - Restore a key from a backup
+//	POST   RouteSign            - Sign data with an ML-DSA key
+//	POST   RouteVerify          - Verify a signature with an ML-DSA key
+//	GET    RouteSealStatus      - Report barrier seal status
+//	POST   RouteInit            - Generate the master key and unseal key shares
+//	POST   RouteUnseal          - Submit an unseal key share
+//	POST   RouteTokenCreate     - Create a token
+//	GET    RouteTokenLookupSelf - Look up the calling token
+//	POST   RouteTokenRevoke     - Revoke a token by ID or accessor
+//	POST   RouteTokenRevokeSelf - Revoke the calling token
+//	POST   RoutePolicy          - Create or replace an ACL policy
+//	GET    RoutePolicy          - Read an ACL policy
+//	DELETE RoutePolicy          - Delete an ACL policy
+//	GET    RoutePolicies        - List ACL policies
 const (
-	// POST: Create a new Kyber key pair
+	// POST/GET/DELETE: Create, read or delete a key
 	RouteCreateKey = "/transit/keys/{name}"
+	// GET: List keys
+	RouteKeys = "/transit/keys"
+	// POST: Rotate a key (add a new version)
+	RouteRotateKey = "/transit/keys/{name}/rotate"
+	// POST: Update the configuration of a key
+	RouteKeyConfig = "/transit/keys/{name}/config"
+	// POST: Permanently delete old versions of a key
+	RouteTrimKey = "/transit/keys/{name}/trim"
 	// POST: Encrypt data with Kyber
 	RouteEncrypt = "/transit/encrypt/{name}"
 	// POST: Decrypt data with Kyber
 	RouteDecrypt = "/transit/decrypt/{name}"
+	// POST: Re-encrypt ciphertext with the latest key version
+	RouteRewrap = "/transit/rewrap/{name}"
+	// POST: Generate a data key wrapped under a key ({type} is "plaintext" or "wrapped")
+	RouteDataKey = "/transit/datakey/{type:plaintext|wrapped}/{name}"
+	// GET: Export the public or private keys of all versions ({type} is "public-key" or "private-key")
+	RouteExportKey = "/transit/export/{type:public-key|private-key}/{name}"
+	// GET: Export the public or private key of one version
+	RouteExportVersion = "/transit/export/{type:public-key|private-key}/{name}/{version}"
+	// GET: Back up a key with all of its versions in plaintext
+	RouteBackup = "/transit/backup/{name}"
+	// POST: Restore a key from a backup
+	RouteRestore = "/transit/restore/{name}"
+	// POST: Sign data with an ML-DSA key
+	RouteSign = "/transit/sign/{name}"
+	// POST: Verify a signature with an ML-DSA key
+	RouteVerify = "/transit/verify/{name}"
+	// GET: Report whether the barrier is initialized and sealed
+	RouteSealStatus = "/sys/seal-status"
+	// POST: Generate the master key and split it into unseal key shares
+	RouteInit = "/sys/init"
+	// POST: Submit an unseal key share
+	RouteUnseal = "/sys/unseal"
+	// POST: Create a token
+	RouteTokenCreate = "/auth/token/create"
+	// GET: Look up the calling token
+	RouteTokenLookupSelf = "/auth/token/lookup-self"
+	// POST: Revoke a token by ID or accessor
+	RouteTokenRevoke = "/auth/token/revoke"
+	// POST: Revoke the calling token
+	RouteTokenRevokeSelf = "/auth/token/revoke-self"
+	// POST/GET/DELETE: Write, read or delete an ACL policy
+	RoutePolicy = "/sys/policy/{name}"
+	// GET: List ACL policies
+	RoutePolicies = "/sys/policy"
 
 	// Names for mux routes (used for URL building)
-	RouteNameCreateKey = "createKey"
-	RouteNameEncrypt   = "encrypt"
-	RouteNameDecrypt   = "decrypt"
+	RouteNameCreateKey       = "createKey"
+	RouteNameReadKey         = "readKey"
+	RouteNameDeleteKey       = "deleteKey"
+	RouteNameListKeys        = "listKeys"
+	RouteNameRotateKey       = "rotateKey"
+	RouteNameKeyConfig       = "keyConfig"
+	RouteNameTrimKey         = "trimKey"
+	RouteNameEncrypt         = "encrypt"
+	RouteNameDecrypt         = "decrypt"
+	RouteNameRewrap          = "rewrap"
+	RouteNameDataKey         = "dataKey"
+	RouteNameExportKey       = "exportKey"
+	RouteNameExportVersion   = "exportVersion"
+	RouteNameBackup          = "backup"
+	RouteNameRestore         = "restore"
+	RouteNameSign            = "sign"
+	RouteNameVerify          = "verify"
+	RouteNameSealStatus      = "sealStatus"
+	RouteNameInit            = "init"
+	RouteNameUnseal          = "unseal"
+	RouteNameTokenCreate     = "tokenCreate"
+	RouteNameTokenLookupSelf = "tokenLookupSelf"
+	RouteNameTokenRevoke     = "tokenRevoke"
+	RouteNameTokenRevokeSelf = "tokenRevokeSelf"
+	RouteNamePolicyWrite     = "policyWrite"
+	RouteNamePolicyRead      = "policyRead"
+	RouteNamePolicyDelete    = "policyDelete"
+	RouteNamePolicyList      = "policyList"
 )
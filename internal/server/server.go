@@ -8,11 +8,42 @@ import (
 
 // NewRouter returns a fully configured HTTP router for the Kyber Transit API.
 // Routes are named to allow URL building via mux.Route.URL in tests and other code.
+// /sys/init, /sys/seal-status, /sys/unseal and /health are served without authentication.
+// All other routes return 503 while the barrier is sealed, are audited, require a bearer
+// token and are authorized against the token's ACL policies.
 func NewRouter() *mux.Router {
 	r := mux.NewRouter()
-	r.HandleFunc(routes.RouteCreateKey, handlers.CreateKeyHandler).Methods("POST").Name(routes.RouteNameCreateKey)
-	r.HandleFunc(routes.RouteEncrypt, handlers.EncryptHandler).Methods("POST").Name(routes.RouteNameEncrypt)
-	r.HandleFunc(routes.RouteDecrypt, handlers.DecryptHandler).Methods("POST").Name(routes.RouteNameDecrypt)
+	r.HandleFunc(routes.RouteSealStatus, handlers.SealStatusHandler).Methods("GET").Name(routes.RouteNameSealStatus)
+	r.HandleFunc(routes.RouteInit, handlers.InitHandler).Methods("POST").Name(routes.RouteNameInit)
+	r.HandleFunc(routes.RouteUnseal, handlers.UnsealHandler).Methods("POST").Name(routes.RouteNameUnseal)
 	r.HandleFunc("/health", handlers.HealthHandler).Methods("GET")
+
+	api := r.NewRoute().Subrouter()
+	api.Use(handlers.RequireUnsealed, handlers.Audit, handlers.RequireToken, handlers.Authorize)
+	api.HandleFunc(routes.RouteTokenCreate, handlers.TokenCreateHandler).Methods("POST").Name(routes.RouteNameTokenCreate)
+	api.HandleFunc(routes.RouteTokenLookupSelf, handlers.TokenLookupSelfHandler).Methods("GET").Name(routes.RouteNameTokenLookupSelf)
+	api.HandleFunc(routes.RouteTokenRevoke, handlers.TokenRevokeHandler).Methods("POST").Name(routes.RouteNameTokenRevoke)
+	api.HandleFunc(routes.RouteTokenRevokeSelf, handlers.TokenRevokeSelfHandler).Methods("POST").Name(routes.RouteNameTokenRevokeSelf)
+	api.HandleFunc(routes.RoutePolicies, handlers.PolicyListHandler).Methods("GET").Name(routes.RouteNamePolicyList)
+	api.HandleFunc(routes.RoutePolicy, handlers.PolicyWriteHandler).Methods("POST").Name(routes.RouteNamePolicyWrite)
+	api.HandleFunc(routes.RoutePolicy, handlers.PolicyReadHandler).Methods("GET").Name(routes.RouteNamePolicyRead)
+	api.HandleFunc(routes.RoutePolicy, handlers.PolicyDeleteHandler).Methods("DELETE").Name(routes.RouteNamePolicyDelete)
+	api.HandleFunc(routes.RouteCreateKey, handlers.CreateKeyHandler).Methods("POST").Name(routes.RouteNameCreateKey)
+	api.HandleFunc(routes.RouteCreateKey, handlers.ReadKeyHandler).Methods("GET").Name(routes.RouteNameReadKey)
+	api.HandleFunc(routes.RouteCreateKey, handlers.DeleteKeyHandler).Methods("DELETE").Name(routes.RouteNameDeleteKey)
+	api.HandleFunc(routes.RouteKeys, handlers.ListKeysHandler).Methods("GET").Name(routes.RouteNameListKeys)
+	api.HandleFunc(routes.RouteRotateKey, handlers.RotateKeyHandler).Methods("POST").Name(routes.RouteNameRotateKey)
+	api.HandleFunc(routes.RouteKeyConfig, handlers.ConfigKeyHandler).Methods("POST").Name(routes.RouteNameKeyConfig)
+	api.HandleFunc(routes.RouteTrimKey, handlers.TrimKeyHandler).Methods("POST").Name(routes.RouteNameTrimKey)
+	api.HandleFunc(routes.RouteEncrypt, handlers.EncryptHandler).Methods("POST").Name(routes.RouteNameEncrypt)
+	api.HandleFunc(routes.RouteDecrypt, handlers.DecryptHandler).Methods("POST").Name(routes.RouteNameDecrypt)
+	api.HandleFunc(routes.RouteRewrap, handlers.RewrapHandler).Methods("POST").Name(routes.RouteNameRewrap)
+	api.HandleFunc(routes.RouteDataKey, handlers.DataKeyHandler).Methods("POST").Name(routes.RouteNameDataKey)
+	api.HandleFunc(routes.RouteExportKey, handlers.ExportKeyHandler).Methods("GET").Name(routes.RouteNameExportKey)
+	api.HandleFunc(routes.RouteExportVersion, handlers.ExportKeyHandler).Methods("GET").Name(routes.RouteNameExportVersion)
+	api.HandleFunc(routes.RouteBackup, handlers.BackupKeyHandler).Methods("GET").Name(routes.RouteNameBackup)
+	api.HandleFunc(routes.RouteRestore, handlers.RestoreKeyHandler).Methods("POST").Name(routes.RouteNameRestore)
+	api.HandleFunc(routes.RouteSign, handlers.SignHandler).Methods("POST").Name(routes.RouteNameSign)
+	api.HandleFunc(routes.RouteVerify, handlers.VerifyHandler).Methods("POST").Name(routes.RouteNameVerify)
 	return r
 }
@@ -1,17 +1,44 @@
 package server
 
 import (
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
 
+	"github.com/dezween/ElevexaCodingChallenge2/internal/handlers"
 	"github.com/dezween/ElevexaCodingChallenge2/internal/routes"
+	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
-func TestServerRoutes(t *testing.T) {
+// newUnsealedRouter resets handler state, initializes it with a single unseal key
+// and returns a router whose barrier is unsealed, together with the root token.
+func newUnsealedRouter(t *testing.T) (*mux.Router, string) {
+	t.Helper()
+	handlers.ResetKeyStore()
 	router := NewRouter()
+	req := httptest.NewRequest("POST", routes.RouteInit, strings.NewReader(`{"secret_shares":1,"secret_threshold":1}`))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var initResp struct {
+		Keys      []string `json:"keys"`
+		RootToken string   `json:"root_token"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &initResp))
+
+	req = httptest.NewRequest("POST", routes.RouteUnseal, strings.NewReader(`{"key":"`+initResp.Keys[0]+`"}`))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	return router, initResp.RootToken
+}
+
+func TestServerRoutes(t *testing.T) {
+	router, rootToken := newUnsealedRouter(t)
 
 	// Test that all main endpoints are registered and respond (even if with error)
 	cases := []struct {
@@ -22,16 +49,30 @@ func TestServerRoutes(t *testing.T) {
 	}{
 		{"POST", routes.RouteCreateKey, "", http.StatusCreated},
 		{"POST", routes.RouteCreateKey, "", http.StatusConflict}, // duplicate
-		{"POST", routes.RouteEncrypt, `{"plaintext":"abc"}`, http.StatusOK},
-		{"POST", "/transit/encrypt/unknown", `{"plaintext":"abc"}`, http.StatusNotFound},
+		{"POST", routes.RouteRotateKey, "", http.StatusOK},
+		{"POST", "/transit/keys/unknown/rotate", "", http.StatusNotFound},
+		{"POST", routes.RouteTrimKey, `{"min_available_version":1}`, http.StatusOK},
+		{"POST", "/transit/keys/unknown/trim", `{"min_available_version":1}`, http.StatusNotFound},
+		{"POST", routes.RouteEncrypt, `{"plaintext":"YWJj"}`, http.StatusOK},
+		{"POST", "/transit/encrypt/unknown", `{"plaintext":"YWJj"}`, http.StatusNotFound},
 		{"POST", routes.RouteDecrypt, `{"ciphertext":"bad","encdata":"bad"}`, http.StatusBadRequest},
 		{"POST", "/transit/decrypt/unknown", `{"ciphertext":"bad","encdata":"bad"}`, http.StatusNotFound},
+		{"GET", "/transit/export/public-key/testserver/1", "", http.StatusOK},
+		{"GET", "/transit/export/private-key/testserver", "", http.StatusBadRequest}, // not exportable
+		{"GET", "/transit/export/public-key/unknown", "", http.StatusNotFound},
+		{"GET", routes.RouteBackup, "", http.StatusBadRequest}, // plaintext backup not allowed
+		{"GET", "/transit/backup/unknown", "", http.StatusNotFound},
+		{"POST", routes.RouteRestore, `{"backup":"!!!"}`, http.StatusBadRequest},
+		{"POST", routes.RouteSign, `{"input":"YQ=="}`, http.StatusBadRequest}, // not a signing key
+		{"POST", "/transit/sign/unknown", `{"input":"YQ=="}`, http.StatusNotFound},
+		{"POST", "/transit/verify/unknown", `{"input":"YQ==","signature":"sig:v1:YQ=="}`, http.StatusNotFound},
 	}
 
 	for _, tc := range cases {
 		url := strings.ReplaceAll(tc.url, "{name}", "testserver")
 		req := httptest.NewRequest(tc.method, url, strings.NewReader(tc.body))
 		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+rootToken)
 		w := httptest.NewRecorder()
 		router.ServeHTTP(w, req)
 		assert.Equal(t, tc.wantStatus, w.Code, "route %s %s", tc.method, url)
@@ -54,3 +95,61 @@ func TestHealthCheck(t *testing.T) {
 	assert.Equal(t, http.StatusOK, w.Code)
 	assert.Equal(t, "ok", w.Body.String())
 }
+
+func TestSealedServer(t *testing.T) {
+	handlers.ResetKeyStore()
+	router := NewRouter()
+
+	cases := []struct {
+		method     string
+		url        string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{"GET", routes.RouteSealStatus, "", http.StatusOK, `{"initialized":false,"sealed":true,"t":0,"n":0,"progress":0}`},
+		{"POST", "/transit/keys/sealed", "", http.StatusServiceUnavailable, `{"error":"Server is sealed"}`},
+		{"POST", "/transit/encrypt/sealed", `{"plaintext":"YWJj"}`, http.StatusServiceUnavailable, `{"error":"Server is sealed"}`},
+		{"GET", "/health", "", http.StatusOK, "ok"},
+	}
+
+	for _, tc := range cases {
+		req := httptest.NewRequest(tc.method, tc.url, strings.NewReader(tc.body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, tc.wantStatus, w.Code, "route %s %s", tc.method, tc.url)
+		assert.Equal(t, tc.wantBody, strings.TrimSpace(w.Body.String()), "route %s %s", tc.method, tc.url)
+	}
+}
+
+func TestAuthentication(t *testing.T) {
+	router, rootToken := newUnsealedRouter(t)
+
+	cases := []struct {
+		name       string
+		method     string
+		url        string
+		auth       string
+		wantStatus int
+	}{
+		{"no token", "POST", "/transit/keys/authkey", "", http.StatusUnauthorized},
+		{"unknown token", "POST", "/transit/keys/authkey", "Bearer kt.unknown", http.StatusUnauthorized},
+		{"wrong scheme", "POST", "/transit/keys/authkey", "Basic " + rootToken, http.StatusUnauthorized},
+		{"token lookup without token", "GET", routes.RouteTokenLookupSelf, "", http.StatusUnauthorized},
+		{"root token", "POST", "/transit/keys/authkey", "Bearer " + rootToken, http.StatusCreated},
+		{"health without token", "GET", "/health", "", http.StatusOK},
+		{"seal status without token", "GET", routes.RouteSealStatus, "", http.StatusOK},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.url, nil)
+			if tc.auth != "" {
+				req.Header.Set("Authorization", tc.auth)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tc.wantStatus, w.Code)
+		})
+	}
+}
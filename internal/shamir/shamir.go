@@ -0,0 +1,163 @@
+// Package shamir implements Shamir's secret sharing over GF(256).
+//
+// Each byte of the secret is the constant term of an independent random polynomial
+// of degree threshold-1. A share holds the evaluations of all polynomials at one
+// non-zero x coordinate, followed by that coordinate as the last byte.
+package shamir
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+)
+
+// ShareOverhead is the number of bytes a share adds to the secret length (the x coordinate).
+const ShareOverhead = 1
+
+// Split divides secret into parts shares, any threshold of which reconstruct it.
+func Split(secret []byte, parts, threshold int) ([][]byte, error) {
+	switch {
+	case len(secret) == 0:
+		return nil, errors.New("shamir: secret must not be empty")
+	case threshold < 2:
+		return nil, errors.New("shamir: threshold must be at least 2")
+	case parts < threshold:
+		return nil, errors.New("shamir: parts must not be less than threshold")
+	case parts > 255:
+		return nil, errors.New("shamir: parts must not exceed 255")
+	}
+
+	xs, err := randomCoordinates(parts)
+	if err != nil {
+		return nil, err
+	}
+	shares := make([][]byte, parts)
+	for i := range shares {
+		shares[i] = make([]byte, len(secret)+ShareOverhead)
+		shares[i][len(secret)] = xs[i]
+	}
+
+	coeffs := make([]byte, threshold)
+	for b, s := range secret {
+		if _, err := rand.Read(coeffs[1:]); err != nil {
+			return nil, fmt.Errorf("shamir: failed to generate coefficients: %w", err)
+		}
+		coeffs[0] = s
+		for i, x := range xs {
+			shares[i][b] = evaluate(coeffs, x)
+		}
+	}
+	return shares, nil
+}
+
+// Combine reconstructs the secret from at least threshold distinct shares.
+// Supplying fewer shares than the threshold yields an unrelated value, not an error.
+func Combine(shares [][]byte) ([]byte, error) {
+	if len(shares) < 2 {
+		return nil, errors.New("shamir: at least two shares are required")
+	}
+	size := len(shares[0])
+	if size <= ShareOverhead {
+		return nil, errors.New("shamir: shares are too short")
+	}
+	xs := make([]byte, len(shares))
+	seen := make(map[byte]bool, len(shares))
+	for i, share := range shares {
+		if len(share) != size {
+			return nil, errors.New("shamir: shares must have the same length")
+		}
+		x := share[size-1]
+		if x == 0 || seen[x] {
+			return nil, errors.New("shamir: duplicate or invalid share")
+		}
+		seen[x] = true
+		xs[i] = x
+	}
+
+	secret := make([]byte, size-ShareOverhead)
+	ys := make([]byte, len(shares))
+	for b := range secret {
+		for i, share := range shares {
+			ys[i] = share[b]
+		}
+		secret[b] = interpolateAtZero(xs, ys)
+	}
+	return secret, nil
+}
+
+// randomCoordinates returns n distinct, non-zero, randomly ordered x coordinates.
+func randomCoordinates(n int) ([]byte, error) {
+	perm := make([]byte, 255)
+	for i := range perm {
+		perm[i] = byte(i + 1)
+	}
+	// Fisher-Yates shuffle driven by crypto/rand.
+	buf := make([]byte, 2)
+	for i := len(perm) - 1; i > 0; i-- {
+		if _, err := rand.Read(buf); err != nil {
+			return nil, fmt.Errorf("shamir: failed to generate coordinates: %w", err)
+		}
+		j := int(uint16(buf[0])<<8|uint16(buf[1])) % (i + 1)
+		perm[i], perm[j] = perm[j], perm[i]
+	}
+	return perm[:n], nil
+}
+
+// evaluate returns the polynomial with the given coefficients (constant term first) at x.
+func evaluate(coeffs []byte, x byte) byte {
+	var y byte
+	for i := len(coeffs) - 1; i >= 0; i-- {
+		y = add(mul(y, x), coeffs[i])
+	}
+	return y
+}
+
+// interpolateAtZero evaluates the Lagrange polynomial through (xs[i], ys[i]) at x = 0.
+func interpolateAtZero(xs, ys []byte) byte {
+	var result byte
+	for i := range xs {
+		basis := byte(1)
+		for j := range xs {
+			if i == j {
+				continue
+			}
+			// basis *= x_j / (x_j - x_i); subtraction is XOR in GF(256).
+			basis = mul(basis, div(xs[j], add(xs[j], xs[i])))
+		}
+		result = add(result, mul(ys[i], basis))
+	}
+	return result
+}
+
+// add adds two elements of GF(256).
+func add(a, b byte) byte {
+	return a ^ b
+}
+
+// mul multiplies two elements of GF(256) modulo x^8 + x^4 + x^3 + x + 1,
+// without data-dependent branches.
+func mul(a, b byte) byte {
+	var p byte
+	for i := 0; i < 8; i++ {
+		p ^= a & -(b & 1)
+		carry := -(a >> 7)
+		a = (a << 1) ^ (0x1b & carry)
+		b >>= 1
+	}
+	return p
+}
+
+// div divides a by a non-zero b in GF(256).
+func div(a, b byte) byte {
+	return mul(a, inverse(b))
+}
+
+// inverse returns the multiplicative inverse of a non-zero element (a^254).
+func inverse(a byte) byte {
+	result := byte(1)
+	for i := 0; i < 7; i++ {
+		a = mul(a, a)
+		result = mul(result, a)
+	}
+	return result
+}
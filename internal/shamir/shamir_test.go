@@ -0,0 +1,123 @@
+package shamir
+
+import (
+	"crypto/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// combinations returns every k-element subset of indices 0..n-1.
+func combinations(n, k int) [][]int {
+	var result [][]int
+	var rec func(start int, current []int)
+	rec = func(start int, current []int) {
+		if len(current) == k {
+			result = append(result, append([]int(nil), current...))
+			return
+		}
+		for i := start; i < n; i++ {
+			rec(i+1, append(current, i))
+		}
+	}
+	rec(0, nil)
+	return result
+}
+
+func TestSplitCombine_AnyThresholdSubset(t *testing.T) {
+	secret := make([]byte, 32)
+	_, err := rand.Read(secret)
+	require.NoError(t, err)
+
+	tests := []struct {
+		parts     int
+		threshold int
+	}{
+		{2, 2},
+		{3, 2},
+		{5, 3},
+		{6, 6},
+	}
+
+	for _, tt := range tests {
+		shares, err := Split(secret, tt.parts, tt.threshold)
+		require.NoError(t, err)
+		require.Len(t, shares, tt.parts)
+		for _, s := range shares {
+			assert.Len(t, s, len(secret)+ShareOverhead)
+		}
+		for k := tt.threshold; k <= tt.parts; k++ {
+			for _, subset := range combinations(tt.parts, k) {
+				selected := make([][]byte, 0, k)
+				for _, i := range subset {
+					selected = append(selected, shares[i])
+				}
+				got, err := Combine(selected)
+				require.NoError(t, err)
+				assert.Equal(t, secret, got, "parts=%d threshold=%d subset=%v", tt.parts, tt.threshold, subset)
+			}
+		}
+	}
+}
+
+func TestCombine_BelowThresholdDoesNotReveal(t *testing.T) {
+	secret := []byte("a secret that needs three shares")
+	shares, err := Split(secret, 5, 3)
+	require.NoError(t, err)
+	got, err := Combine(shares[:2])
+	require.NoError(t, err)
+	assert.NotEqual(t, secret, got)
+}
+
+func TestSplitErrors_TableDriven(t *testing.T) {
+	tests := []struct {
+		name      string
+		secret    []byte
+		parts     int
+		threshold int
+	}{
+		{"empty secret", nil, 3, 2},
+		{"threshold below two", []byte("s"), 3, 1},
+		{"parts below threshold", []byte("s"), 2, 3},
+		{"too many parts", []byte("s"), 256, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Split(tt.secret, tt.parts, tt.threshold)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestCombineErrors_TableDriven(t *testing.T) {
+	shares, err := Split([]byte("secret"), 3, 2)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		shares [][]byte
+	}{
+		{"single share", shares[:1]},
+		{"duplicate share", [][]byte{shares[0], shares[0]}},
+		{"length mismatch", [][]byte{shares[0], shares[1][:3]}},
+		{"too short", [][]byte{{1}, {2}}},
+		{"zero coordinate", [][]byte{{1, 0}, {2, 1}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Combine(tt.shares)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestFieldArithmetic(t *testing.T) {
+	assert.Equal(t, byte(0xc1), mul(0x57, 0x83), "FIPS-197 example")
+	for a := 1; a < 256; a++ {
+		assert.Equal(t, byte(1), mul(byte(a), inverse(byte(a))), "a=%d", a)
+		assert.Equal(t, byte(a), div(mul(byte(a), 0x35), 0x35), "a=%d", a)
+	}
+}
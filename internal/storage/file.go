@@ -0,0 +1,147 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// tempPrefix marks in-progress writes; such files are ignored by List.
+const tempPrefix = ".tmp-"
+
+// File is a Storage that keeps one file per entry in a single directory.
+// Writes are atomic: data is written to a temporary file, fsynced, renamed over
+// the target and the directory is fsynced, so entries survive crashes and restarts.
+type File struct {
+	dir string
+	mu  sync.RWMutex
+}
+
+// NewFile opens (creating if needed) a file-system storage rooted at dir.
+func NewFile(dir string) (*File, error) {
+	if dir == "" {
+		return nil, errors.New("storage: directory must not be empty")
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("storage: failed to create directory: %w", err)
+	}
+	return &File{dir: dir}, nil
+}
+
+// Get returns the value stored at key, or ErrNotFound.
+func (f *File) Get(key string) ([]byte, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	value, err := os.ReadFile(f.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("storage: failed to read %q: %w", key, err)
+	}
+	return value, nil
+}
+
+// Put atomically stores value at key.
+func (f *File) Put(key string, value []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	tmp, err := os.CreateTemp(f.dir, tempPrefix+"*")
+	if err != nil {
+		return fmt.Errorf("storage: failed to create temp file: %w", err)
+	}
+	tmpName := tmp.Name()
+	defer func() {
+		// No-op after a successful rename.
+		_ = os.Remove(tmpName)
+	}()
+	if _, err := tmp.Write(value); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("storage: failed to write %q: %w", key, err)
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("storage: failed to sync %q: %w", key, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("storage: failed to close %q: %w", key, err)
+	}
+	if err := os.Rename(tmpName, f.path(key)); err != nil {
+		return fmt.Errorf("storage: failed to rename %q: %w", key, err)
+	}
+	return f.syncDir()
+}
+
+// Delete removes the entry at key.
+func (f *File) Delete(key string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	err := os.Remove(f.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("storage: failed to delete %q: %w", key, err)
+	}
+	return f.syncDir()
+}
+
+// List returns all keys starting with prefix, sorted.
+func (f *File) List(prefix string) ([]string, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	entries, err := os.ReadDir(f.dir)
+	if err != nil {
+		return nil, fmt.Errorf("storage: failed to list directory: %w", err)
+	}
+	keys := make([]string, 0)
+	for _, e := range entries {
+		if e.IsDir() || strings.HasPrefix(e.Name(), tempPrefix) {
+			continue
+		}
+		key, err := url.PathUnescape(e.Name())
+		if err != nil {
+			continue
+		}
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// path maps a key to a file name in the storage directory.
+// Slashes and other special characters are escaped so every entry is a flat file;
+// a leading dot is escaped so keys cannot collide with temporary files.
+func (f *File) path(key string) string {
+	name := url.PathEscape(key)
+	if strings.HasPrefix(name, ".") {
+		name = "%2E" + name[1:]
+	}
+	return filepath.Join(f.dir, name)
+}
+
+// syncDir fsyncs the storage directory so renames and removals are durable.
+// Windows does not support syncing directories; renames there are already durable.
+func (f *File) syncDir() error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	d, err := os.Open(f.dir)
+	if err != nil {
+		return fmt.Errorf("storage: failed to open directory: %w", err)
+	}
+	defer func() { _ = d.Close() }()
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("storage: failed to sync directory: %w", err)
+	}
+	return nil
+}
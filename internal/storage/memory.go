@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Memory is a volatile in-memory Storage. All entries are lost when the process exits.
+// Intended for tests and development.
+type Memory struct {
+	mu      sync.RWMutex
+	entries map[string][]byte
+}
+
+// NewMemory creates an empty in-memory storage.
+func NewMemory() *Memory {
+	return &Memory{entries: make(map[string][]byte)}
+}
+
+// Get returns a copy of the value stored at key, or ErrNotFound.
+func (m *Memory) Get(key string) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	value, ok := m.entries[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return append([]byte(nil), value...), nil
+}
+
+// Put stores a copy of value at key.
+func (m *Memory) Put(key string, value []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries[key] = append([]byte(nil), value...)
+	return nil
+}
+
+// Delete removes the entry at key.
+func (m *Memory) Delete(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.entries, key)
+	return nil
+}
+
+// List returns all keys starting with prefix, sorted.
+func (m *Memory) List(prefix string) ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	keys := make([]string, 0)
+	for k := range m.entries {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
@@ -0,0 +1,21 @@
+// Package storage defines the key-value persistence layer used by the transit engine
+// and provides in-memory and file-system implementations.
+package storage
+
+import "errors"
+
+// ErrNotFound is returned by Get when no entry exists for the key.
+var ErrNotFound = errors.New("storage: entry not found")
+
+// Storage is a thread-safe key-value store.
+// Keys are slash-separated paths such as "keys/my-key"; values are opaque bytes.
+type Storage interface {
+	// Get returns the value stored at key, or ErrNotFound.
+	Get(key string) ([]byte, error)
+	// Put stores value at key, replacing any previous value.
+	Put(key string, value []byte) error
+	// Delete removes the entry at key. Deleting a missing key is not an error.
+	Delete(key string) error
+	// List returns all keys starting with prefix, sorted lexicographically.
+	List(prefix string) ([]string, error)
+}
@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStorage_TableDriven(t *testing.T) {
+	backends := []struct {
+		name string
+		new  func(t *testing.T) Storage
+	}{
+		{"memory", func(t *testing.T) Storage { return NewMemory() }},
+		{"file", func(t *testing.T) Storage {
+			s, err := NewFile(t.TempDir())
+			require.NoError(t, err)
+			return s
+		}},
+	}
+
+	for _, b := range backends {
+		t.Run(b.name, func(t *testing.T) {
+			s := b.new(t)
+
+			_, err := s.Get("keys/missing")
+			assert.ErrorIs(t, err, ErrNotFound)
+
+			require.NoError(t, s.Put("keys/a", []byte("one")))
+			require.NoError(t, s.Put("keys/b", []byte("two")))
+			require.NoError(t, s.Put("other/.hidden", []byte("three")))
+			require.NoError(t, s.Put("keys/a", []byte("updated")))
+
+			value, err := s.Get("keys/a")
+			require.NoError(t, err)
+			assert.Equal(t, []byte("updated"), value)
+
+			keys, err := s.List("keys/")
+			require.NoError(t, err)
+			assert.Equal(t, []string{"keys/a", "keys/b"}, keys)
+
+			all, err := s.List("")
+			require.NoError(t, err)
+			assert.Equal(t, []string{"keys/a", "keys/b", "other/.hidden"}, all)
+
+			require.NoError(t, s.Delete("keys/a"))
+			require.NoError(t, s.Delete("keys/a"), "deleting a missing key is not an error")
+			_, err = s.Get("keys/a")
+			assert.ErrorIs(t, err, ErrNotFound)
+		})
+	}
+}
+
+func TestFile_SurvivesReopen(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewFile(dir)
+	require.NoError(t, err)
+	require.NoError(t, s.Put("keys/persisted", []byte("value")))
+
+	reopened, err := NewFile(dir)
+	require.NoError(t, err)
+	value, err := reopened.Get("keys/persisted")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value"), value)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "no temporary files should be left behind")
+}
+
+func TestNewFile_EmptyDir(t *testing.T) {
+	_, err := NewFile("")
+	assert.Error(t, err)
+}
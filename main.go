@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
@@ -10,12 +11,56 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/dezween/ElevexaCodingChallenge2/internal/audit"
 	"github.com/dezween/ElevexaCodingChallenge2/internal/config"
+	"github.com/dezween/ElevexaCodingChallenge2/internal/handlers"
 	"github.com/dezween/ElevexaCodingChallenge2/internal/server"
+	"github.com/dezween/ElevexaCodingChallenge2/internal/storage"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "audit-verify" {
+		os.Exit(auditVerify(os.Args[2:]))
+	}
+
 	cfg := config.LoadConfig()
+
+	var backend storage.Storage = storage.NewMemory()
+	if cfg.StoragePath != "" {
+		fileStorage, err := storage.NewFile(cfg.StoragePath)
+		if err != nil {
+			log.Fatalf("failed to open storage: %v", err)
+		}
+		backend = fileStorage
+		log.Printf("Using file storage at %s", cfg.StoragePath)
+	} else {
+		log.Println("[WARN] KYBER_STORAGE_PATH is not set; keys are kept in memory and lost on restart")
+	}
+	handlers.UseStorage(backend)
+
+	var sinks []audit.Sink
+	if cfg.AuditFile != "" {
+		fileSink, err := audit.NewFile(cfg.AuditFile)
+		if err != nil {
+			log.Fatalf("failed to open audit log: %v", err)
+		}
+		defer fileSink.Close()
+		sinks = append(sinks, fileSink)
+		log.Printf("Writing audit log to %s", cfg.AuditFile)
+	}
+	if cfg.AuditStdout {
+		sinks = append(sinks, audit.NewWriter(os.Stdout))
+	}
+	if len(sinks) == 0 {
+		log.Println("[WARN] auditing is disabled; set KYBER_AUDIT_FILE or KYBER_AUDIT_STDOUT")
+	}
+	handlers.UseAudit(sinks...)
+	handlers.SetBatchWorkers(cfg.BatchWorkers)
+	handlers.SetBatchLimits(cfg.BatchMaxItems, cfg.MaxRequestBytes)
+	log.Println("Server starts sealed; initialize with POST /sys/init and unseal with POST /sys/unseal")
+
+	stopAutoRotation := handlers.StartAutoRotation(cfg.RotationCheckInterval)
+
 	router := server.NewRouter()
 
 	httpServer := &http.Server{
@@ -42,5 +87,28 @@ func main() {
 	if err := httpServer.Shutdown(ctx); err != nil {
 		log.Fatalf("Server forced to shutdown: %v", err)
 	}
+	stopAutoRotation()
 	log.Println("Server exited gracefully")
 }
+
+// auditVerify implements "kyber-server audit-verify <file>": it walks the hash
+// chain of an audit log and reports the first break. Returns the exit code.
+func auditVerify(args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "usage: kyber-server audit-verify <audit log file>")
+		return 2
+	}
+	f, err := os.Open(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to open audit log: %v\n", err)
+		return 1
+	}
+	defer f.Close()
+	count, err := audit.Verify(f)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "audit log invalid after %d entries: %v\n", count, err)
+		return 1
+	}
+	fmt.Printf("audit log OK: %d entries\n", count)
+	return 0
+}